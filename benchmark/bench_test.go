@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/dghubble/sling"
@@ -61,6 +62,7 @@ func BenchmarkPOSTWithJSON(b *testing.B) {
 	b.Run("Gorequest", benchmarkGorequest_POSTWithJSON)
 	b.Run("Grequests", benchmarkGrequests_POSTWithJSON)
 	b.Run("Hx", benchmarkHx_POSTWithJSON)
+	b.Run("HxPrepared", benchmarkHxPrepared_POSTWithJSON)
 	b.Run("NetHTTP", benchmarkNetHTTP_POSTWithJSON)
 }
 
@@ -71,6 +73,7 @@ func BenchmarkGETWithQuery(b *testing.B) {
 	b.Run("Gorequest", benchmarkGorequest_GETWithQuery)
 	b.Run("Grequests", benchmarkGrequests_GETWithQuery)
 	b.Run("Hx", benchmarkHx_GETWithQuery)
+	b.Run("HxPrepared", benchmarkHxPrepared_GETWithQuery)
 	b.Run("NetHTTP", benchmarkNetHTTP_GETWithQuery)
 }
 
@@ -305,6 +308,52 @@ func benchmarkHx_POSTWithJSON(b *testing.B) {
 	}
 }
 
+func benchmarkHxPrepared_GETWithQuery(b *testing.B) {
+	url, closeServer := setupServer()
+	defer closeServer()
+	client, err := hx.NewClient().Prepare(
+		hx.Query("message", "It works!"),
+	)
+	if err != nil {
+		b.Fatalf("returned %v, want nil", err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var msg Message
+		err := client.Get(context.Background(), url,
+			hx.Query("user_id", fmt.Sprint(i)),
+			hx.WhenSuccess(hx.AsJSON(&msg)),
+			hx.WhenFailure(hx.AsJSONError(&Error{})),
+		)
+		if err != nil {
+			b.Errorf("returned %v, want nil", err)
+		}
+	}
+}
+
+func benchmarkHxPrepared_POSTWithJSON(b *testing.B) {
+	url, closeServer := setupServer()
+	defer closeServer()
+	client, err := hx.NewClient().Prepare()
+	if err != nil {
+		b.Fatalf("returned %v, want nil", err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var msg Message
+		err := client.Post(context.Background(), url,
+			hx.JSON(&Message{UserID: i, Message: "It works!"}),
+			hx.WhenSuccess(hx.AsJSON(&msg)),
+			hx.WhenFailure(hx.AsJSONError(&Error{})),
+		)
+		if err != nil {
+			b.Errorf("returned %v, want nil", err)
+		}
+	}
+}
+
 func benchmarkNetHTTP_GETWithQuery(b *testing.B) {
 	u, closeServer := setupServer()
 	defer closeServer()
@@ -352,3 +401,61 @@ func benchmarkNetHTTP_POSTWithJSON(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkHxOverhead measures allocations by hx itself with a stub transport that does not send requests.
+func BenchmarkHxOverhead(b *testing.B) {
+	b.Run("Hx", benchmarkHx_Overhead)
+	b.Run("HxPrepared", benchmarkHxPrepared_Overhead)
+}
+
+var (
+	stubTransport = hx.TransportFunc(func(r *http.Request, _ http.RoundTripper) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"user_id":1,"message":"It works!"}`)),
+			Request:    r,
+		}, nil
+	})
+	nopInterceptor = hx.InterceptFunc(func(cli *http.Client, req *http.Request, next hx.RequestFunc) (*http.Response, error) {
+		return next(cli, req)
+	})
+)
+
+func benchmarkHx_Overhead(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		var msg Message
+		client := hx.NewClient(stubTransport, nopInterceptor, nopInterceptor)
+		err := client.Get(context.Background(), "https://api.example.com/messages",
+			hx.Query("user_id", fmt.Sprint(i)),
+			hx.Query("message", "It works!"),
+			hx.WhenSuccess(hx.AsJSON(&msg)),
+			hx.WhenFailure(hx.AsJSONError(&Error{})),
+		)
+		if err != nil {
+			b.Errorf("returned %v, want nil", err)
+		}
+	}
+}
+
+func benchmarkHxPrepared_Overhead(b *testing.B) {
+	client, err := hx.NewClient(stubTransport, nopInterceptor, nopInterceptor).Prepare(
+		hx.Query("message", "It works!"),
+	)
+	if err != nil {
+		b.Fatalf("returned %v, want nil", err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var msg Message
+		err := client.Get(context.Background(), "https://api.example.com/messages",
+			hx.Query("user_id", fmt.Sprint(i)),
+			hx.WhenSuccess(hx.AsJSON(&msg)),
+			hx.WhenFailure(hx.AsJSONError(&Error{})),
+		)
+		if err != nil {
+			b.Errorf("returned %v, want nil", err)
+		}
+	}
+}
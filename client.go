@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
 )
 
 func Get(ctx context.Context, url string, opts ...Option) error {
@@ -27,8 +28,27 @@ func Delete(ctx context.Context, url string, opts ...Option) error {
 	return NewClient().Delete(ctx, url, opts...)
 }
 
+func Head(ctx context.Context, url string, opts ...Option) error {
+	return NewClient().Head(ctx, url, opts...)
+}
+
+func Options(ctx context.Context, url string, opts ...Option) error {
+	return NewClient().Options(ctx, url, opts...)
+}
+
+// Request sends an HTTP request with an arbitrary method.
+func Request(ctx context.Context, meth string, url string, opts ...Option) error {
+	return NewClient().Request(ctx, meth, url, opts...)
+}
+
+// Do sends an HTTP request and returns the response processed by response handlers.
+func Do(ctx context.Context, meth string, url string, opts ...Option) (*Response, error) {
+	return NewClient().Do(ctx, meth, url, opts...)
+}
+
 type Client struct {
 	opts []Option
+	tmpl *Config
 }
 
 // NewClient creates a new http client instance.
@@ -58,39 +78,144 @@ func (c *Client) Delete(ctx context.Context, url string, opts ...Option) error {
 	return c.request(ctx, http.MethodDelete, url, opts...)
 }
 
+func (c *Client) Head(ctx context.Context, url string, opts ...Option) error {
+	return c.request(ctx, http.MethodHead, url, opts...)
+}
+
+func (c *Client) Options(ctx context.Context, url string, opts ...Option) error {
+	return c.request(ctx, http.MethodOptions, url, opts...)
+}
+
+// Request sends an HTTP request with an arbitrary method.
+//  err := cli.Request(ctx, "PURGE", "/assets/app.js",
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func (c *Client) Request(ctx context.Context, meth string, url string, opts ...Option) error {
+	return c.request(ctx, meth, url, opts...)
+}
+
+// Do sends an HTTP request and returns the response processed by response handlers.
+// The part of the body that was not consumed by handlers is read into memory, so it can be obtained with Response.Bytes.
+// Do returns a non-nil Response whenever handlers produce one, even if the returned error is not nil.
+//  resp, err := cli.Do(ctx, http.MethodPost, "/api/contents",
+//  	hx.JSON(in),
+//  	hx.WhenSuccess(hx.AsJSON(&out)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+//  if err != nil {
+//  	// ...
+//  }
+//  loc := resp.Header.Get("Location")
+func (c *Client) Do(ctx context.Context, meth string, url string, opts ...Option) (*Response, error) {
+	startedAt := time.Now()
+
+	cfg, err := c.newRequestConfig(url, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// response handlers can close the body before the rest is read by newResponse
+	cfg.ResponseHandlers = append([]ResponseHandler{trackResponseBody}, cfg.ResponseHandlers...)
+
+	resp, err := cfg.DoRequest(ctx, meth)
+	if resp == nil {
+		return nil, err
+	}
+
+	r, rerr := newResponse(resp, startedAt)
+	if err == nil && rerr != nil {
+		err = &ResponseError{Response: resp, Err: rerr}
+	}
+
+	return r, err
+}
+
 // With clones the current client and applies the given options.
 func (c *Client) With(opts ...Option) *Client {
 	newOpts := make([]Option, 0, len(c.opts)+len(opts))
 	newOpts = append(newOpts, c.opts...)
 	newOpts = append(newOpts, opts...)
-	return NewClient(newOpts...)
+	return &Client{opts: newOpts, tmpl: c.tmpl}
+}
+
+// Prepare resolves DefaultOptions, the client options and the given options once,
+// and returns a new client that holds the result as an immutable template.
+// The returned client only applies per-call options to a copy of the template on each request,
+// so it is suitable for hot paths that send many requests with the same static options.
+// Options that set a request body should be given per call since a body can be read only once.
+//  cli, err := hx.NewClient(hx.BaseURL(baseURL)).Prepare(
+//  	hx.Bearer(token),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+//  if err != nil {
+//  	// ...
+//  }
+//  err = cli.Get(ctx, hx.Path("api", "contents", id),
+//  	hx.WhenSuccess(hx.AsJSON(&cont)),
+//  )
+func (c *Client) Prepare(opts ...Option) (*Client, error) {
+	cfg, err := c.newConfig()
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.Apply(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{tmpl: cfg}, nil
 }
 
 func (c *Client) request(ctx context.Context, meth string, url string, opts ...Option) error {
-	cfg, err := NewConfig()
+	cfg, err := c.newRequestConfig(url, opts...)
 	if err != nil {
 		return err
 	}
-	err = cfg.Apply(c.opts...)
+	resp, err := cfg.DoRequest(ctx, meth)
 	if err != nil {
 		return err
 	}
+	if resp == nil {
+		return nil
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	return nil
+}
+
+func (c *Client) newRequestConfig(url string, opts ...Option) (*Config, error) {
+	cfg, err := c.newConfig()
+	if err != nil {
+		return nil, err
+	}
 	err = cfg.Apply(URL(url))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = cfg.Apply(opts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return cfg, nil
+}
 
-	resp, err := cfg.DoRequest(ctx, meth)
-	if err != nil {
-		return err
+func (c *Client) newConfig() (*Config, error) {
+	var cfg *Config
+
+	if c.tmpl != nil {
+		cfg = c.tmpl.clone()
+	} else {
+		var err error
+		cfg, err = NewConfig()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	io.Copy(ioutil.Discard, resp.Body)
-	resp.Body.Close()
+	err := cfg.Apply(c.opts...)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	return cfg, nil
 }
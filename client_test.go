@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -20,13 +21,13 @@ import (
 func TestClient(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch {
-		case r.Method == http.MethodGet && r.URL.Path == "/ping":
+		case (r.Method == http.MethodGet || r.Method == http.MethodHead) && r.URL.Path == "/ping":
 			w.Write([]byte("pong"))
 		case r.URL.Path == "/method":
 			if want, got := r.URL.Query().Get("method"), r.Method; got != want {
 				w.WriteHeader(http.StatusBadRequest)
 			}
-		case r.Method == http.MethodGet && r.URL.Path == "/echo":
+		case (r.Method == http.MethodGet || r.Method == http.MethodHead) && r.URL.Path == "/echo":
 			msg := r.URL.Query().Get("message")
 			if msg == "" {
 				w.WriteHeader(http.StatusBadRequest)
@@ -94,7 +95,7 @@ func TestClient(t *testing.T) {
 			t.Error("returned nil, want an error")
 		} else if reqErr, ok := err.(*hx.ResponseError); !ok {
 			t.Errorf("returned %v, want *hx.ResponseError", err)
-		} else if reqErr.Unwrap() == reqErr {
+		} else if reqErr.Unwrap() == nil {
 			t.Error("returned error wrapped no errors")
 		}
 	}
@@ -104,7 +105,7 @@ func TestClient(t *testing.T) {
 			t.Error("returned nil, want an error")
 		} else if reqErr, ok := err.(*hx.ResponseError); !ok {
 			t.Errorf("returned %v, want *hx.ResponseError", err)
-		} else if reqErr.Unwrap() != reqErr {
+		} else if reqErr.Unwrap() != nil {
 			t.Errorf("returned error wrapped %v, want nil", reqErr.Unwrap())
 		}
 	}
@@ -164,6 +165,33 @@ func TestClient(t *testing.T) {
 				t.Errorf("returned %v, want nil", err)
 			}
 		})
+		t.Run(http.MethodHead, func(t *testing.T) {
+			err := hx.Head(context.Background(), ts.URL+"/method",
+				hx.Query("method", http.MethodHead),
+				hx.WhenFailure(hx.AsError()),
+			)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+		})
+		t.Run(http.MethodOptions, func(t *testing.T) {
+			err := hx.Options(context.Background(), ts.URL+"/method",
+				hx.Query("method", http.MethodOptions),
+				hx.WhenFailure(hx.AsError()),
+			)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+		})
+		t.Run("PURGE", func(t *testing.T) {
+			err := hx.Request(context.Background(), "PURGE", ts.URL+"/method",
+				hx.Query("method", "PURGE"),
+				hx.WhenFailure(hx.AsError()),
+			)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+		})
 	})
 
 	t.Run("receive json", func(t *testing.T) {
@@ -182,6 +210,37 @@ func TestClient(t *testing.T) {
 		}
 	})
 
+	t.Run("receive json with HEAD", func(t *testing.T) {
+		var out struct {
+			Message string `json:"message"`
+		}
+		err := hx.NewClient().Head(context.Background(), ts.URL+"/echo",
+			hx.Query("message", "It, Works!"),
+			hx.WhenSuccess(hx.AsJSON(&out)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := out.Message, ""; got != want {
+			t.Errorf("returned %q, want %q", got, want)
+		}
+	})
+
+	t.Run("receive bytes with HEAD", func(t *testing.T) {
+		var out bytes.Buffer
+		err := hx.Head(context.Background(), ts.URL+"/ping",
+			hx.WhenSuccess(hx.AsBytesBuffer(&out)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := out.String(), ""; got != want {
+			t.Errorf("returned %q, want %q", got, want)
+		}
+	})
+
 	t.Run("receive bytes", func(t *testing.T) {
 		var out bytes.Buffer
 		err := hx.Get(context.Background(), ts.URL+"/ping",
@@ -494,3 +553,244 @@ func TestClient_DefaultOptions(t *testing.T) {
 		}
 	})
 }
+
+func TestClient_Do(t *testing.T) {
+	type Post struct {
+		Message string `json:"message"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/posts":
+			var post Post
+			err := json.NewDecoder(r.Body).Decode(&post)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Location", "/posts/1")
+			w.Header().Set("X-Request-Id", "req-1")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(&post)
+		case r.Method == http.MethodGet && r.URL.Path == "/ping":
+			w.Write([]byte("pong"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	cli := hx.NewClient(hx.WhenFailure(hx.AsError()))
+
+	t.Run("with handlers", func(t *testing.T) {
+		var out Post
+		resp, err := cli.Do(context.Background(), http.MethodPost, ts.URL+"/posts",
+			hx.JSON(&Post{Message: "Hello!"}),
+			hx.WhenSuccess(hx.AsJSON(&out)),
+		)
+		if err != nil {
+			t.Fatalf("returned %v, want nil", err)
+		}
+		if got, want := resp.StatusCode, http.StatusCreated; got != want {
+			t.Errorf("returned status %d, want %d", got, want)
+		}
+		if got, want := resp.Header.Get("Location"), "/posts/1"; got != want {
+			t.Errorf("returned Location %q, want %q", got, want)
+		}
+		if got, want := resp.Header.Get("X-Request-Id"), "req-1"; got != want {
+			t.Errorf("returned X-Request-Id %q, want %q", got, want)
+		}
+		if got, want := out.Message, "Hello!"; got != want {
+			t.Errorf("decoded %q, want %q", got, want)
+		}
+		if got := resp.Bytes(); len(got) != 0 {
+			t.Errorf("returned body %q, want empty", got)
+		}
+		if resp.Duration <= 0 {
+			t.Errorf("returned duration %s, want positive", resp.Duration)
+		}
+	})
+
+	t.Run("without handlers", func(t *testing.T) {
+		resp, err := hx.Do(context.Background(), http.MethodGet, ts.URL+"/ping")
+		if err != nil {
+			t.Fatalf("returned %v, want nil", err)
+		}
+		if got, want := string(resp.Bytes()), "pong"; got != want {
+			t.Errorf("returned body %q, want %q", got, want)
+		}
+		var buf bytes.Buffer
+		buf.ReadFrom(resp.Body)
+		if got, want := buf.String(), "pong"; got != want {
+			t.Errorf("read body %q, want %q", got, want)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		resp, err := cli.Do(context.Background(), http.MethodGet, ts.URL+"/notfound")
+		if err == nil {
+			t.Error("returned nil, want an error")
+		}
+		if resp == nil {
+			t.Fatal("returned nil response")
+		}
+		if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+			t.Errorf("returned status %d, want %d", got, want)
+		}
+	})
+}
+
+func TestClient_Prepare(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/echo":
+			q := r.URL.Query()
+			err := json.NewEncoder(w).Encode(map[string]string{
+				"message": r.Header.Get("Message") + strings.Join(q["suffix"], ""),
+			})
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	type Response struct {
+		Message string `json:"message"`
+	}
+
+	u, _ := url.Parse(ts.URL)
+	cli, err := hx.NewClient(hx.BaseURL(u)).Prepare(
+		hx.Header("Message", "foo"),
+		hx.Query("suffix", "!"),
+		hx.WhenFailure(hx.AsError()),
+	)
+	if err != nil {
+		t.Fatalf("returned %v, want nil", err)
+	}
+
+	t.Run("reuse", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			var resp Response
+			err := cli.Get(context.Background(), "/echo",
+				hx.Query("suffix", "?"),
+				hx.WhenSuccess(hx.AsJSON(&resp)),
+			)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if got, want := resp.Message, "foo!?"; got != want {
+				t.Errorf("returned %q, want %q", got, want)
+			}
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		errCh := make(chan error, 10)
+		for i := 0; i < cap(errCh); i++ {
+			go func(i int) {
+				var resp Response
+				err := cli.Get(context.Background(), "/echo",
+					hx.Header("Message", strconv.Itoa(i)),
+					hx.Timeout(time.Duration(i+1)*time.Second),
+					hx.WhenSuccess(hx.AsJSON(&resp)),
+				)
+				if err == nil && resp.Message != strconv.Itoa(i)+"!" {
+					err = errors.New("unexpected message: " + resp.Message)
+				}
+				errCh <- err
+			}(i)
+		}
+		for i := 0; i < cap(errCh); i++ {
+			if err := <-errCh; err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+		}
+	})
+
+	t.Run("With", func(t *testing.T) {
+		var resp Response
+		err := cli.With(hx.Header("Message", "bar")).Get(context.Background(), "/echo",
+			hx.WhenSuccess(hx.AsJSON(&resp)),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := resp.Message, "bar!"; got != want {
+			t.Errorf("returned %q, want %q", got, want)
+		}
+
+		err = cli.Get(context.Background(), "/echo",
+			hx.WhenSuccess(hx.AsJSON(&resp)),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := resp.Message, "foo!"; got != want {
+			t.Errorf("returned %q, want %q", got, want)
+		}
+	})
+
+	t.Run("with interceptors", func(t *testing.T) {
+		var calls []string
+		record := func(name string) hx.Option {
+			return hx.InterceptFunc(func(cli *http.Client, req *http.Request, next hx.RequestFunc) (*http.Response, error) {
+				calls = append(calls, name)
+				return next(cli, req)
+			})
+		}
+		cli, err := cli.With(record("client")).Prepare(record("prepared"))
+		if err != nil {
+			t.Fatalf("returned %v, want nil", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			calls = nil
+			var resp Response
+			err := cli.Get(context.Background(), "/echo",
+				record("call"),
+				hx.WhenSuccess(hx.AsJSON(&resp)),
+			)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if got, want := resp.Message, "foo!"; got != want {
+				t.Errorf("returned %q, want %q", got, want)
+			}
+			if got, want := calls, []string{"client", "prepared", "call"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("called %v, want %v", got, want)
+			}
+		}
+	})
+
+	t.Run("with interceptors replacing contexts", func(t *testing.T) {
+		cli, err := cli.Prepare(hx.InterceptFunc(func(cli *http.Client, req *http.Request, next hx.RequestFunc) (*http.Response, error) {
+			return next(cli, req.WithContext(context.Background()))
+		}))
+		if err != nil {
+			t.Fatalf("returned %v, want nil", err)
+		}
+
+		var resp Response
+		err = cli.Get(context.Background(), "/echo",
+			hx.WhenSuccess(hx.AsJSON(&resp)),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := resp.Message, "foo!"; got != want {
+			t.Errorf("returned %q, want %q", got, want)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := hx.NewClient().Prepare(hx.OptionFunc(func(c *hx.Config) error {
+			return errors.New("error occurred")
+		}))
+		if err == nil {
+			t.Error("returned nil, want an error")
+		}
+	})
+}
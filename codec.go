@@ -0,0 +1,121 @@
+package hx
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// responseDecoder decodes a response body. It receives the response to read parameters of Content-Type (e.g. charset).
+type responseDecoder func(*http.Response, interface{}) error
+
+var decoders = struct {
+	sync.RWMutex
+	m          map[string]responseDecoder
+	mediaTypes []string
+}{m: map[string]responseDecoder{}}
+
+func init() {
+	RegisterDecoder("application/json", func(r io.Reader, v interface{}) error {
+		return DefaultJSONConfig.decode(r, v)
+	})
+	decodeXML := func(r *http.Response, v interface{}) error {
+		return DefaultXMLConfig.decodeResponse(r, v)
+	}
+	registerResponseDecoder("application/xml", decodeXML)
+	registerResponseDecoder("text/xml", decodeXML)
+}
+
+// RegisterDecoder registers a decoder for a media type used by AsAuto and AcceptAuto.
+// Media types with structured syntax suffixes (e.g. application/vnd.api+json) fall back to decoders for application/json or application/xml
+// unless decoders are registered for themselves.
+// Plugins register decoders for their formats on init.
+func RegisterDecoder(mediaType string, f func(io.Reader, interface{}) error) {
+	registerResponseDecoder(mediaType, func(r *http.Response, v interface{}) error { return f(r.Body, v) })
+}
+
+func registerResponseDecoder(mediaType string, f responseDecoder) {
+	mediaType = strings.ToLower(mediaType)
+
+	decoders.Lock()
+	defer decoders.Unlock()
+
+	if _, ok := decoders.m[mediaType]; !ok {
+		decoders.mediaTypes = append(decoders.mediaTypes, mediaType)
+	}
+	decoders.m[mediaType] = f
+}
+
+func lookupDecoder(mediaType string) (responseDecoder, bool) {
+	decoders.RLock()
+	defer decoders.RUnlock()
+
+	if f, ok := decoders.m[mediaType]; ok {
+		return f, true
+	}
+	// structured syntax suffixes (RFC 6839)
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if f, ok := decoders.m["application/"+mediaType[i+1:]]; ok {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// UnsupportedMediaTypeError is returned by AsAuto when no decoders are registered for the content type of the response.
+// It is wrapped with ResponseError.
+type UnsupportedMediaTypeError struct {
+	MediaType string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("hx: unsupported media type %q", e.MediaType)
+}
+
+// AsAuto is ResponseHandler that decodes the response body with a decoder registered for its content type.
+//  err := hx.Get(ctx, "https://api.example.com/contents/1",
+//  	hx.AcceptAuto(),
+//  	hx.WhenSuccess(hx.AsAuto(&cont)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func AsAuto(dst interface{}) ResponseHandler {
+	return func(r *http.Response, err error) (*http.Response, error) {
+		if r == nil || err != nil {
+			return r, err
+		}
+		if !hasBody(r) {
+			return r, nil
+		}
+		defer r.Body.Close()
+
+		mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, &ResponseError{Response: r, Err: &UnsupportedMediaTypeError{MediaType: r.Header.Get("Content-Type")}}
+		}
+		decode, ok := lookupDecoder(mt)
+		if !ok {
+			return nil, &ResponseError{Response: r, Err: &UnsupportedMediaTypeError{MediaType: mt}}
+		}
+
+		err = decode(r, dst)
+		if err != nil {
+			return nil, &ResponseError{Response: r, Err: err}
+		}
+		return r, nil
+	}
+}
+
+// AcceptAuto sets Accept header to media types that have registered decoders, in the order of registration.
+func AcceptAuto() Option {
+	return HandleRequest(func(r *http.Request) (*http.Request, error) {
+		decoders.RLock()
+		accept := strings.Join(decoders.mediaTypes, ", ")
+		decoders.RUnlock()
+
+		r.Header.Set("Accept", accept)
+		return r, nil
+	})
+}
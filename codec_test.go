@@ -0,0 +1,125 @@
+package hx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/izumin5210/hx"
+)
+
+func TestAsAuto(t *testing.T) {
+	type Content struct {
+		Body string `json:"body" xml:"body"`
+	}
+
+	hx.RegisterDecoder("application/x-test", func(r io.Reader, v interface{}) error {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		v.(*Content).Body = "test:" + string(data)
+		return nil
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Accept", r.Header.Get("Accept"))
+		ct := r.URL.Query().Get("content_type")
+		w.Header().Set("Content-Type", ct)
+		switch {
+		case strings.Contains(ct, "charset=ISO-8859-1"):
+			w.Write([]byte("<content><body>caf\xe9</body></content>"))
+		case strings.Contains(ct, "json"):
+			w.Write([]byte(`{"body":"hello"}`))
+		case strings.Contains(ct, "xml"):
+			w.Write([]byte(`<content><body>hello</body></content>`))
+		default:
+			w.Write([]byte("hello"))
+		}
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		contentType string
+		want        string
+		unsupported bool
+	}{
+		{contentType: "application/json", want: "hello"},
+		{contentType: "application/json; charset=utf-8", want: "hello"},
+		{contentType: "application/vnd.api+json", want: "hello"},
+		{contentType: "application/xml", want: "hello"},
+		{contentType: "text/xml; charset=utf-8", want: "hello"},
+		{contentType: "application/soap+xml", want: "hello"},
+		{contentType: "application/x-test", want: "test:hello"},
+		{contentType: "text/html", unsupported: true},
+		{contentType: "", unsupported: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.contentType, func(t *testing.T) {
+			var cont Content
+			err := hx.Get(context.Background(), ts.URL,
+				hx.Query("content_type", tc.contentType),
+				hx.WhenSuccess(hx.AsAuto(&cont)),
+				hx.WhenFailure(hx.AsError()),
+			)
+
+			if tc.unsupported {
+				var (
+					respErr *hx.ResponseError
+					mtErr   *hx.UnsupportedMediaTypeError
+				)
+				if !errors.As(err, &respErr) || !errors.As(err, &mtErr) {
+					t.Errorf("returned %v, want *hx.UnsupportedMediaTypeError wrapped with *hx.ResponseError", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if got, want := cont.Body, tc.want; got != want {
+				t.Errorf("decoded %q, want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("non-UTF-8 charset", func(t *testing.T) {
+		defer func(cfg *hx.XMLConfig) { hx.DefaultXMLConfig = cfg }(hx.DefaultXMLConfig)
+		hx.DefaultXMLConfig = &hx.XMLConfig{CharsetReader: latin1Reader}
+
+		var cont Content
+		err := hx.Get(context.Background(), ts.URL,
+			hx.Query("content_type", "text/xml; charset=ISO-8859-1"),
+			hx.WhenSuccess(hx.AsAuto(&cont)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := cont.Body, "café"; got != want {
+			t.Errorf("decoded %q, want %q", got, want)
+		}
+	})
+
+	t.Run("AcceptAuto", func(t *testing.T) {
+		resp, err := hx.Do(context.Background(), http.MethodGet, ts.URL,
+			hx.Query("content_type", "application/json"),
+			hx.AcceptAuto(),
+		)
+		if err != nil {
+			t.Fatalf("returned %v, want nil", err)
+		}
+		accept := resp.Header.Get("X-Accept")
+		for _, mt := range []string{"application/json", "application/x-test"} {
+			if !strings.Contains(accept, mt) {
+				t.Errorf("sent Accept %q, want to contain %q", accept, mt)
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package hx
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
@@ -15,9 +16,14 @@ type Config struct {
 	RequestHandlers  []RequestHandler
 	ResponseHandlers []ResponseHandler
 	Interceptors     []Interceptor
-}
 
-var newRequest func(ctx context.Context, meth, url string, body io.Reader) (*http.Request, error)
+	// MaxResponseBytes limits the size of response bodies. It is unlimited if zero.
+	MaxResponseBytes int64
+
+	// GetBody returns a new copy of Body. It is set to http.Request.GetBody to allow retrying and redirecting requests.
+	// It is not necessary for *bytes.Buffer, *bytes.Reader and *strings.Reader since net/http handles them.
+	GetBody func() (io.ReadCloser, error)
+}
 
 func NewConfig() (*Config, error) {
 	cfg := &Config{URL: new(url.URL), HTTPClient: new(http.Client), QueryParams: url.Values{}}
@@ -38,27 +44,61 @@ func (cfg *Config) Apply(opts ...Option) error {
 	return nil
 }
 
+// clone returns a copy of the config that can be modified by options without affecting the original one.
+// Slices are copied on append since their capacity is clipped.
+func (cfg *Config) clone() *Config {
+	newCfg := *cfg
+
+	if cfg.HTTPClient != nil {
+		cli := *cfg.HTTPClient
+		newCfg.HTTPClient = &cli
+	}
+
+	newCfg.QueryParams = make(url.Values, len(cfg.QueryParams))
+	for k, v := range cfg.QueryParams {
+		newCfg.QueryParams[k] = v[:len(v):len(v)]
+	}
+
+	newCfg.RequestHandlers = cfg.RequestHandlers[:len(cfg.RequestHandlers):len(cfg.RequestHandlers)]
+	newCfg.ResponseHandlers = cfg.ResponseHandlers[:len(cfg.ResponseHandlers):len(cfg.ResponseHandlers)]
+	newCfg.Interceptors = cfg.Interceptors[:len(cfg.Interceptors):len(cfg.Interceptors)]
+
+	return &newCfg
+}
+
 func (cfg *Config) DoRequest(ctx context.Context, meth string) (*http.Response, error) {
+	u := cfg.URL
 	if len(cfg.QueryParams) > 0 {
-		q, err := url.ParseQuery(cfg.URL.RawQuery)
-		if err != nil {
-			return nil, err
-		}
-		for k, values := range cfg.QueryParams {
-			for _, v := range values {
-				q.Add(k, v)
+		newURL := *cfg.URL
+		if cfg.URL.RawQuery == "" {
+			newURL.RawQuery = cfg.QueryParams.Encode()
+		} else {
+			q, err := url.ParseQuery(cfg.URL.RawQuery)
+			if err != nil {
+				return nil, err
 			}
+			for k, values := range cfg.QueryParams {
+				for _, v := range values {
+					q.Add(k, v)
+				}
+			}
+			newURL.RawQuery = q.Encode()
 		}
-		cfg.URL.RawQuery = q.Encode()
+		u = &newURL
 	}
 
-	req, err := newRequest(ctx, meth, cfg.URL.String(), cfg.Body)
+	req, err := http.NewRequestWithContext(ctx, meth, u.String(), cfg.Body)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.GetBody != nil {
+		req.GetBody = cfg.GetBody
+	}
 
-	f := combineInterceptors(cfg.Interceptors).Wrap(cfg.doRequest)
-	return f(cfg.HTTPClient, req)
+	if len(cfg.Interceptors) == 0 {
+		return cfg.doRequest(cfg.HTTPClient, req)
+	}
+	return combineInterceptors(cfg.Interceptors).DoRequest(cfg.HTTPClient, req, cfg.doRequest)
 }
 
 func (cfg *Config) doRequest(cli *http.Client, req *http.Request) (resp *http.Response, err error) {
@@ -70,6 +110,9 @@ func (cfg *Config) doRequest(cli *http.Client, req *http.Request) (resp *http.Re
 	}
 
 	resp, err = cli.Do(req)
+	if err == nil && cfg.MaxResponseBytes > 0 {
+		resp, err = limitResponseBody(resp, cfg.MaxResponseBytes)
+	}
 
 	for _, h := range cfg.ResponseHandlers {
 		resp, err = h(resp, err)
@@ -77,3 +120,50 @@ func (cfg *Config) doRequest(cli *http.Client, req *http.Request) (resp *http.Re
 
 	return resp, err
 }
+
+// ErrResponseTooLarge is returned when a response body is larger than Config.MaxResponseBytes.
+// It is wrapped with ResponseError.
+var ErrResponseTooLarge = errors.New("hx: response body too large")
+
+// limitResponseBody fails fast if Content-Length is larger than n,
+// and makes the body fail with ErrResponseTooLarge after n bytes otherwise.
+func limitResponseBody(r *http.Response, n int64) (*http.Response, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return r, nil
+	}
+	if r.ContentLength > n {
+		_ = r.Body.Close()
+		return nil, &ResponseError{Response: r, Err: ErrResponseTooLarge}
+	}
+	r.Body = &limitedBody{ReadCloser: r.Body, n: n}
+	return r, nil
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	n   int64 // remaining bytes
+	err error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// read one more byte to detect bodies larger than the limit
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.n {
+		b.n -= int64(n)
+		b.err = err
+		return n, err
+	}
+	n = int(b.n)
+	b.n = 0
+	b.err = ErrResponseTooLarge
+	return n, b.err
+}
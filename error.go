@@ -0,0 +1,148 @@
+package hx
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// ResponseError is an error with a response from the server.
+//  err := hx.Get(ctx, "https://api.example.com/contents/1",
+//  	hx.WhenSuccess(hx.AsJSON(&cont)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+//  if errors.Is(err, hx.ErrStatus(http.StatusNotFound)) {
+//  	// handle not found
+//  }
+type ResponseError struct {
+	Response *http.Response
+	Err      error
+	// Body is the first bytes of the response body captured by AsError. It is included in the error message.
+	Body []byte
+}
+
+// maxErrorSnippetBytes is the max size of the response body included in error messages.
+const maxErrorSnippetBytes = 512
+
+func (e *ResponseError) Error() string {
+	var b strings.Builder
+	if req := e.request(); req != nil && req.URL != nil {
+		fmt.Fprintf(&b, "%s %s: ", req.Method, req.URL.Redacted())
+	}
+	switch {
+	case e.Response == nil:
+		if e.Err != nil {
+			b.WriteString(e.Err.Error())
+		} else {
+			b.WriteString("the server responded with an error")
+		}
+	default:
+		b.WriteString("the server responded with status ")
+		if e.Response.Status != "" {
+			b.WriteString(e.Response.Status)
+		} else {
+			fmt.Fprintf(&b, "%d", e.Response.StatusCode)
+		}
+		if e.Err != nil {
+			b.WriteString(": ")
+			b.WriteString(e.Err.Error())
+		}
+	}
+	// the body of problem details has been included in the message of Err
+	if _, ok := e.Err.(*Problem); ok {
+		return b.String()
+	}
+	if snippet := e.snippet(); snippet != "" {
+		b.WriteString(": ")
+		b.WriteString(snippet)
+	}
+	return b.String()
+}
+
+func (e *ResponseError) snippet() string {
+	body := e.Body
+	truncated := len(body) > maxErrorSnippetBytes
+	if truncated {
+		body = body[:maxErrorSnippetBytes]
+		// avoid splitting a multi-byte character
+		for len(body) > 0 && !utf8.Valid(body) {
+			body = body[:len(body)-1]
+		}
+	}
+	s := strings.TrimSpace(string(body))
+	if truncated {
+		s += "..."
+	}
+	return s
+}
+
+func (e *ResponseError) Unwrap() error { return e.Err }
+
+// Is reports whether the response status matches a target created by ErrStatus.
+func (e *ResponseError) Is(target error) bool {
+	s, ok := target.(StatusError)
+	return ok && e.Response != nil && e.Response.StatusCode == int(s)
+}
+
+func (e *ResponseError) request() *http.Request {
+	if e.Response == nil {
+		return nil
+	}
+	return e.Response.Request
+}
+
+// StatusCode returns the status code of the response. It returns 0 if the response is unknown.
+func (e *ResponseError) StatusCode() int {
+	if e.Response == nil {
+		return 0
+	}
+	return e.Response.StatusCode
+}
+
+// Header returns the header of the response. It returns nil if the response is unknown.
+func (e *ResponseError) Header() http.Header {
+	if e.Response == nil {
+		return nil
+	}
+	return e.Response.Header
+}
+
+// Method returns the method of the request. It returns an empty string if the request is unknown.
+func (e *ResponseError) Method() string {
+	if req := e.request(); req != nil {
+		return req.Method
+	}
+	return ""
+}
+
+// URL returns the URL of the request. It returns nil if the request is unknown.
+func (e *ResponseError) URL() *url.URL {
+	if req := e.request(); req != nil {
+		return req.URL
+	}
+	return nil
+}
+
+func (e *ResponseError) IsBadRequest() bool      { return e.StatusCode() == http.StatusBadRequest }
+func (e *ResponseError) IsUnauthorized() bool    { return e.StatusCode() == http.StatusUnauthorized }
+func (e *ResponseError) IsForbidden() bool       { return e.StatusCode() == http.StatusForbidden }
+func (e *ResponseError) IsNotFound() bool        { return e.StatusCode() == http.StatusNotFound }
+func (e *ResponseError) IsConflict() bool        { return e.StatusCode() == http.StatusConflict }
+func (e *ResponseError) IsTooManyRequests() bool { return e.StatusCode() == http.StatusTooManyRequests }
+func (e *ResponseError) IsClientError() bool     { return e.StatusCode()/100 == 4 }
+func (e *ResponseError) IsServerError() bool     { return e.StatusCode()/100 == 5 }
+
+// StatusError is a sentinel error that matches ResponseError with the status code via errors.Is.
+type StatusError int
+
+// ErrStatus returns a sentinel error that matches ResponseError with a given status code.
+//  if errors.Is(err, hx.ErrStatus(http.StatusNotFound)) {
+//  	// ...
+//  }
+func ErrStatus(code int) error { return StatusError(code) }
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("status %d %s", int(e), http.StatusText(int(e)))
+}
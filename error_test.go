@@ -0,0 +1,122 @@
+package hx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/izumin5210/hx"
+)
+
+func TestResponseError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/long":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(strings.Repeat("x", 1000)))
+		default:
+			w.Header().Set("X-Request-Id", "abc")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"not found"}` + "\n"))
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("not found", func(t *testing.T) {
+		err := hx.Get(context.Background(), ts.URL+"/users/1", hx.WhenFailure(hx.AsError()))
+
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Fatalf("returned %v, want *hx.ResponseError", err)
+		}
+		wantMsg := "GET " + ts.URL + `/users/1: the server responded with status 404 Not Found: {"message":"not found"}`
+		if got, want := err.Error(), wantMsg; got != want {
+			t.Errorf("Error() returned %q, want %q", got, want)
+		}
+		if got, want := respErr.StatusCode(), http.StatusNotFound; got != want {
+			t.Errorf("StatusCode() returned %d, want %d", got, want)
+		}
+		if got, want := respErr.Method(), http.MethodGet; got != want {
+			t.Errorf("Method() returned %q, want %q", got, want)
+		}
+		if got, want := respErr.URL().String(), ts.URL+"/users/1"; got != want {
+			t.Errorf("URL() returned %q, want %q", got, want)
+		}
+		if got, want := respErr.Header().Get("X-Request-Id"), "abc"; got != want {
+			t.Errorf("Header() has %q, want %q", got, want)
+		}
+		if !respErr.IsNotFound() || !respErr.IsClientError() || respErr.IsServerError() {
+			t.Error("status helpers returned unexpected results")
+		}
+		if got := respErr.Unwrap(); got != nil {
+			t.Errorf("Unwrap() returned %v, want nil", got)
+		}
+
+		if !errors.Is(err, hx.ErrStatus(http.StatusNotFound)) {
+			t.Errorf("errors.Is(err, ErrStatus(404)) returned false, want true")
+		}
+		if errors.Is(err, hx.ErrStatus(http.StatusBadRequest)) {
+			t.Errorf("errors.Is(err, ErrStatus(400)) returned true, want false")
+		}
+		if errors.Is(err, io.EOF) {
+			t.Errorf("errors.Is(err, io.EOF) returned true, want false")
+		}
+
+		// the body is still readable
+		data, _ := io.ReadAll(respErr.Response.Body)
+		if got, want := string(data), `{"message":"not found"}`+"\n"; got != want {
+			t.Errorf("body is %q, want %q", got, want)
+		}
+	})
+
+	t.Run("long body", func(t *testing.T) {
+		err := hx.Get(context.Background(), ts.URL+"/long", hx.WhenFailure(hx.AsError()))
+		if err == nil {
+			t.Fatal("returned nil, want an error")
+		}
+		if got, want := err.Error(), ": "+strings.Repeat("x", 512)+"..."; !strings.HasSuffix(got, want) {
+			t.Errorf("Error() returned %q, want suffix %q", got, want)
+		}
+		if !errors.Is(err, hx.ErrStatus(http.StatusBadGateway)) {
+			t.Errorf("errors.Is(err, ErrStatus(502)) returned false, want true")
+		}
+	})
+
+	t.Run("wrapped error", func(t *testing.T) {
+		errNotFound := errors.New("not found")
+		err := hx.Get(context.Background(), ts.URL+"/users/1",
+			hx.WhenStatus(func(r *http.Response, err error) (*http.Response, error) {
+				return nil, &hx.ResponseError{Response: r, Err: errNotFound}
+			}, http.StatusNotFound),
+		)
+		if !errors.Is(err, errNotFound) {
+			t.Errorf("returned %v, want %v", err, errNotFound)
+		}
+		if !errors.Is(err, hx.ErrStatus(http.StatusNotFound)) {
+			t.Errorf("errors.Is(err, ErrStatus(404)) returned false, want true")
+		}
+	})
+
+	t.Run("without response", func(t *testing.T) {
+		respErr := &hx.ResponseError{Err: errors.New("failed")}
+		if got, want := respErr.Error(), "failed"; got != want {
+			t.Errorf("Error() returned %q, want %q", got, want)
+		}
+		if got, want := respErr.StatusCode(), 0; got != want {
+			t.Errorf("StatusCode() returned %d, want %d", got, want)
+		}
+		if got := respErr.Header(); got != nil {
+			t.Errorf("Header() returned %v, want nil", got)
+		}
+		if respErr.IsNotFound() || respErr.IsClientError() || respErr.IsServerError() {
+			t.Errorf("status helpers returned true, want false")
+		}
+		if got, want := (&hx.ResponseError{}).Error(), "the server responded with an error"; got != want {
+			t.Errorf("Error() returned %q, want %q", got, want)
+		}
+	})
+}
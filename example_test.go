@@ -47,3 +47,39 @@ func ExampleGet() {
 	// Output:
 	// It Works!
 }
+
+func ExampleGetJSON() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/echo":
+			err := json.NewEncoder(w).Encode(map[string]string{
+				"message": r.URL.Query().Get("message"),
+			})
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	type Message struct {
+		Message string `json:"message"`
+	}
+
+	ctx := context.Background()
+	out, err := hx.GetJSON[Message](
+		ctx,
+		ts.URL+"/echo",
+		hx.Query("message", "It Works!"),
+	)
+	if err != nil {
+		// Handle errors...
+	}
+	fmt.Println(out.Message)
+
+	// Output:
+	// It Works!
+}
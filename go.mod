@@ -1,3 +1,3 @@
 module github.com/izumin5210/hx
 
-go 1.13
+go 1.18
@@ -2,6 +2,7 @@ package hxutil
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"net/http"
 )
@@ -19,3 +20,19 @@ func DrainResponseBody(r *http.Response) error {
 	r.Body = ioutil.NopCloser(&buf)
 	return nil
 }
+
+// DrainResponseBodyN is the same as DrainResponseBody, but it keeps only the first n bytes of the body.
+// The rest of the body is not read, so it is safe with endless bodies.
+func DrainResponseBodyN(r *http.Response, n int64) error {
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(io.LimitReader(r.Body, n))
+	if err != nil {
+		return err
+	}
+	err = r.Body.Close()
+	if err != nil {
+		return err
+	}
+	r.Body = ioutil.NopCloser(&buf)
+	return nil
+}
@@ -51,3 +51,36 @@ func TestDrainResponseBody(t *testing.T) {
 		}
 	})
 }
+
+func TestDrainResponseBodyN(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		n    int64
+		want string
+	}{
+		{n: 4, want: "0123"},
+		{n: 10, want: "0123456789"},
+		{n: 20, want: "0123456789"},
+	} {
+		resp, err := http.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err = DrainResponseBodyN(resp, tc.n)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		} else if got, want := string(data), tc.want; got != want {
+			t.Errorf("returned %q, want %q", got, want)
+		}
+	}
+}
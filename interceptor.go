@@ -29,16 +29,16 @@ func (i InterceptorFunc) Wrap(f RequestFunc) RequestFunc {
 	return func(c *http.Client, r *http.Request) (*http.Response, error) { return i.DoRequest(c, r, f) }
 }
 
-func combineInterceptors(interceptors []Interceptor) Interceptor {
-	n := len(interceptors)
+// chainInterceptors wraps f with interceptors once, so the returned function can be called many times without wrapping again.
+func chainInterceptors(interceptors []Interceptor, f RequestFunc) RequestFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		f = interceptors[i].Wrap(f)
+	}
+	return f
+}
 
+func combineInterceptors(interceptors []Interceptor) Interceptor {
 	return InterceptorFunc(func(cli *http.Client, req *http.Request, f RequestFunc) (*http.Response, error) {
-		next := f
-
-		for i := n - 1; i >= 0; i-- {
-			next = interceptors[i].Wrap(next)
-		}
-
-		return next(cli, req)
+		return chainInterceptors(interceptors, f)(cli, req)
 	})
 }
@@ -22,6 +22,7 @@ func (c *JSONConfig) JSON(v interface{}) Option {
 			return err
 		}
 		cfg.Body = r
+		cfg.GetBody = nil
 		return contentTypeJSON.ApplyOption(cfg)
 	})
 }
@@ -31,6 +32,9 @@ func (c *JSONConfig) AsJSON(v interface{}) ResponseHandler {
 		if r == nil || err != nil {
 			return r, err
 		}
+		if !hasBody(r) {
+			return r, nil
+		}
 
 		defer r.Body.Close()
 		err = c.decode(r.Body, v)
@@ -46,6 +50,9 @@ func (c *JSONConfig) AsJSONError(dst error) ResponseHandler {
 		if r == nil || err != nil {
 			return r, err
 		}
+		if !hasBody(r) {
+			return nil, &ResponseError{Response: r, Err: dst}
+		}
 		err = c.decode(r.Body, dst)
 		if err != nil {
 			return nil, &ResponseError{Response: r, Err: err}
@@ -0,0 +1,166 @@
+package hx
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// MultipartPart is a part of multipart/form-data request bodies.
+type MultipartPart struct {
+	name     string
+	filename string
+	header   textproto.MIMEHeader
+	open     func() (io.Reader, error)
+	// reusable reports whether open can be called more than once.
+	reusable bool
+}
+
+// MultipartField creates a form field part.
+func MultipartField(name, value string) *MultipartPart {
+	return &MultipartPart{
+		name:     name,
+		header:   make(textproto.MIMEHeader),
+		open:     func() (io.Reader, error) { return strings.NewReader(value), nil },
+		reusable: true,
+	}
+}
+
+// MultipartFile creates a file part that reads a file on a given path.
+// The file is opened when the part is sent, and it is closed after sent.
+func MultipartFile(name, path string) *MultipartPart {
+	return &MultipartPart{
+		name:     name,
+		filename: filepath.Base(path),
+		header:   textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}},
+		open:     func() (io.Reader, error) { return os.Open(path) },
+		reusable: true,
+	}
+}
+
+// MultipartReader creates a file part that reads a given reader.
+// The reader is not closed even if it implements io.Closer.
+// Requests containing this part cannot be replayed on retries or redirects since the reader can be read only once.
+func MultipartReader(name, filename string, r io.Reader) *MultipartPart {
+	return &MultipartPart{
+		name:     name,
+		filename: filename,
+		header:   textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}},
+		open:     func() (io.Reader, error) { return io.NopCloser(r), nil },
+	}
+}
+
+// Header sets a value to the part header.
+func (p *MultipartPart) Header(k, v string) *MultipartPart {
+	p.header.Set(k, v)
+	return p
+}
+
+// ContentType sets a content type of the part.
+func (p *MultipartPart) ContentType(ct string) *MultipartPart {
+	return p.Header("Content-Type", ct)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (p *MultipartPart) write(mw *multipart.Writer) error {
+	h := make(textproto.MIMEHeader, len(p.header)+1)
+	for k, v := range p.header {
+		h[k] = v
+	}
+	disp := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(p.name))
+	if p.filename != "" {
+		disp += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(p.filename))
+	}
+	h.Set("Content-Disposition", disp)
+
+	r, err := p.open()
+	if err != nil {
+		return err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	w, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// Multipart sets parts to request body as multipart/form-data.
+// The parts are streamed to the request body through io.Pipe, so large files are not buffered in memory.
+// Config.GetBody is also set unless it contains parts created by MultipartReader.
+//  err := hx.Post(ctx, "https://api.example.com/videos",
+//  	hx.Multipart(
+//  		hx.MultipartField("title", "My video"),
+//  		hx.MultipartFile("video", "/path/to/video.mp4").ContentType("video/mp4"),
+//  	),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func Multipart(parts ...*MultipartPart) Option {
+	return OptionFunc(func(c *Config) error {
+		body := newMultipartBody(parts, "")
+		c.Body = body
+		c.GetBody = nil
+
+		reusable := true
+		for _, p := range parts {
+			reusable = reusable && p.reusable
+		}
+		if reusable {
+			boundary := body.mw.Boundary()
+			c.GetBody = func() (io.ReadCloser, error) { return newMultipartBody(parts, boundary), nil }
+		}
+
+		return Header("Content-Type", body.mw.FormDataContentType()).ApplyOption(c)
+	})
+}
+
+// multipartBody is io.ReadCloser that writes multipart parts into a pipe in background.
+// Writing parts is started on first read.
+type multipartBody struct {
+	parts []*MultipartPart
+	mw    *multipart.Writer
+	pr    *io.PipeReader
+	pw    *io.PipeWriter
+	once  sync.Once
+}
+
+// newMultipartBody creates a multipartBody. A random boundary is used if boundary is empty.
+func newMultipartBody(parts []*MultipartPart, boundary string) *multipartBody {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	if boundary != "" {
+		// boundaries generated by multipart.Writer are always valid
+		_ = mw.SetBoundary(boundary)
+	}
+	return &multipartBody{parts: parts, mw: mw, pr: pr, pw: pw}
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	b.once.Do(func() { go b.write() })
+	return b.pr.Read(p)
+}
+
+func (b *multipartBody) Close() error {
+	return b.pr.Close()
+}
+
+func (b *multipartBody) write() {
+	for _, p := range b.parts {
+		err := p.write(b.mw)
+		if err != nil {
+			b.pw.CloseWithError(err)
+			return
+		}
+	}
+	b.pw.CloseWithError(b.mw.Close())
+}
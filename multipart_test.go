@@ -0,0 +1,139 @@
+package hx_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/izumin5210/hx"
+)
+
+func TestMultipart(t *testing.T) {
+	type Part struct {
+		Name        string `json:"name"`
+		Filename    string `json:"filename"`
+		ContentType string `json:"content_type"`
+		Extra       string `json:"extra"`
+		Body        string `json:"body"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload":
+			mr, err := r.MultipartReader()
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			var parts []Part
+			for {
+				p, err := mr.NextPart()
+				if err != nil {
+					break
+				}
+				data, err := ioutil.ReadAll(p)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				parts = append(parts, Part{
+					Name:        p.FormName(),
+					Filename:    p.FileName(),
+					ContentType: p.Header.Get("Content-Type"),
+					Extra:       p.Header.Get("X-Extra"),
+					Body:        string(data),
+				})
+			}
+			json.NewEncoder(w).Encode(parts)
+		case r.Method == http.MethodPost && r.URL.Path == "/redirect":
+			ioutil.ReadAll(r.Body)
+			http.Redirect(w, r, "/upload", http.StatusTemporaryRedirect)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "video.mp4")
+	err := os.WriteFile(path, []byte("video data"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write a file: %v", err)
+	}
+
+	t.Run("simple", func(t *testing.T) {
+		var got []Part
+		err := hx.Post(context.Background(), ts.URL+"/upload",
+			hx.Multipart(
+				hx.MultipartField("title", "My video"),
+				hx.MultipartFile("video", path).ContentType("video/mp4"),
+				hx.MultipartReader("note", `"note".txt`, strings.NewReader("Hello!")).Header("X-Extra", "extra"),
+			),
+			hx.WhenSuccess(hx.AsJSON(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		want := []Part{
+			{Name: "title", Body: "My video"},
+			{Name: "video", Filename: "video.mp4", ContentType: "video/mp4", Body: "video data"},
+			{Name: "note", Filename: `"note".txt`, ContentType: "application/octet-stream", Extra: "extra", Body: "Hello!"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	})
+
+	t.Run("replayed on redirect", func(t *testing.T) {
+		var got []Part
+		err := hx.Post(context.Background(), ts.URL+"/redirect",
+			hx.Multipart(
+				hx.MultipartField("title", "My video"),
+				hx.MultipartFile("video", path).ContentType("video/mp4"),
+			),
+			hx.WhenSuccess(hx.AsJSON(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		want := []Part{
+			{Name: "title", Body: "My video"},
+			{Name: "video", Filename: "video.mp4", ContentType: "video/mp4", Body: "video data"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	})
+
+	t.Run("not replayable with readers", func(t *testing.T) {
+		err := hx.Post(context.Background(), ts.URL+"/redirect",
+			hx.Multipart(
+				hx.MultipartReader("note", "note.txt", strings.NewReader("Hello!")),
+			),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err == nil {
+			t.Error("returned nil, want an error")
+		}
+	})
+
+	t.Run("file not found", func(t *testing.T) {
+		err := hx.Post(context.Background(), ts.URL+"/upload",
+			hx.Multipart(
+				hx.MultipartFile("video", filepath.Join(dir, "missing.mp4")),
+			),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err == nil {
+			t.Error("returned nil, want an error")
+		}
+	})
+}
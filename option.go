@@ -22,6 +22,7 @@ var (
 	}
 
 	contentTypeJSON = Header("Content-Type", "application/json")
+	contentTypeXML  = Header("Content-Type", "application/xml")
 	contentTypeForm = Header("Content-Type", "application/x-www-form-urlencoded")
 )
 
@@ -78,6 +79,7 @@ func Query(k, v string) Option {
 // Body sets data to request body.
 func Body(v interface{}) Option {
 	return OptionFunc(func(c *Config) error {
+		c.GetBody = nil
 		switch v := v.(type) {
 		case io.Reader:
 			c.Body = v
@@ -118,6 +120,9 @@ func Body(v interface{}) Option {
 // JSON sets data to request body as json.
 func JSON(v interface{}) Option { return DefaultJSONConfig.JSON(v) }
 
+// XML sets data to request body as xml.
+func XML(v interface{}) Option { return DefaultXMLConfig.XML(v) }
+
 // HTTPClient sets a HTTP client that used to send HTTP request(s).
 func HTTPClient(cli *http.Client) Option {
 	return OptionFunc(func(c *Config) error {
@@ -146,6 +151,24 @@ func TransportFunc(f func(*http.Request, http.RoundTripper) (*http.Response, err
 	return TransportFrom(hxutil.RoundTripperFunc(f).Wrap)
 }
 
+// MaxResponseBytes limits the size of response bodies to n bytes.
+// A response with larger Content-Length fails before response handlers run, without reading the body.
+// Otherwise, reading the body fails with ErrResponseTooLarge after n bytes.
+//  err := hx.Get(ctx, "https://api.example.com/contents",
+//  	hx.MaxResponseBytes(10<<20),
+//  	hx.WhenSuccess(hx.AsJSON(&contents)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+//  if errors.Is(err, hx.ErrResponseTooLarge) {
+//  	// ...
+//  }
+func MaxResponseBytes(n int64) Option {
+	return OptionFunc(func(c *Config) error {
+		c.MaxResponseBytes = n
+		return nil
+	})
+}
+
 // Timeout sets the max duration for http request(s).
 func Timeout(t time.Duration) Option {
 	return OptionFunc(func(c *Config) error {
@@ -1,9 +1,16 @@
 package hx_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/izumin5210/hx"
 )
@@ -49,3 +56,108 @@ func TestCombineOptions(t *testing.T) {
 		}
 	})
 }
+
+func TestMaxResponseBytes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/ten":
+			w.Write([]byte("0123456789"))
+		case r.Method == http.MethodGet && r.URL.Path == "/endless":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			for r.Context().Err() == nil {
+				_, err := w.Write(bytes.Repeat([]byte("x"), 1024))
+				if err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+				time.Sleep(time.Millisecond)
+			}
+		case r.Method == http.MethodGet && r.URL.Path == "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(bytes.Repeat([]byte("x"), 100<<10))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("within the limit", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := hx.Get(context.Background(), ts.URL+"/ten",
+			hx.MaxResponseBytes(10),
+			hx.WhenSuccess(hx.AsBytesBuffer(&buf)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := buf.String(), "0123456789"; got != want {
+			t.Errorf("received %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Content-Length exceeds the limit", func(t *testing.T) {
+		called := false
+		err := hx.Get(context.Background(), ts.URL+"/ten",
+			hx.MaxResponseBytes(9),
+			hx.WhenSuccess(func(r *http.Response, err error) (*http.Response, error) {
+				called = true
+				return r, err
+			}),
+		)
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("returned %v, want *hx.ResponseError", err)
+		}
+		if !errors.Is(err, hx.ErrResponseTooLarge) {
+			t.Errorf("returned %v, want %v", err, hx.ErrResponseTooLarge)
+		}
+		if called {
+			t.Error("response handlers are called")
+		}
+	})
+
+	t.Run("endless body", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := hx.Get(context.Background(), ts.URL+"/endless",
+			hx.MaxResponseBytes(10<<10),
+			hx.WhenSuccess(hx.AsBytesBuffer(&buf)),
+		)
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("returned %v, want *hx.ResponseError", err)
+		}
+		if !errors.Is(err, hx.ErrResponseTooLarge) {
+			t.Errorf("returned %v, want %v", err, hx.ErrResponseTooLarge)
+		}
+		if got, want := buf.Len(), 10<<10; got != want {
+			t.Errorf("read %d bytes, want %d", got, want)
+		}
+	})
+
+	t.Run("Do", func(t *testing.T) {
+		_, err := hx.Do(context.Background(), http.MethodGet, ts.URL+"/endless",
+			hx.MaxResponseBytes(10<<10),
+		)
+		if !errors.Is(err, hx.ErrResponseTooLarge) {
+			t.Errorf("returned %v, want %v", err, hx.ErrResponseTooLarge)
+		}
+	})
+
+	t.Run("AsError keeps the first bytes", func(t *testing.T) {
+		err := hx.Get(context.Background(), ts.URL+"/error",
+			hx.WhenFailure(hx.AsError()),
+		)
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Fatalf("returned %v, want *hx.ResponseError", err)
+		}
+		data, err := ioutil.ReadAll(respErr.Response.Body)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := string(data), strings.Repeat("x", 64<<10); got != want {
+			t.Errorf("kept %d bytes, want %d bytes", len(got), len(want))
+		}
+	})
+}
@@ -0,0 +1,406 @@
+// A plugin for caching HTTP responses based on RFC 7234.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/hx"
+)
+
+// StatusHeader is a response header that tells whether a response was served from the cache.
+const StatusHeader = "X-Hx-Cache"
+
+// Status represents how a response was served.
+type Status string
+
+const (
+	// StatusMiss means the response was received from the server.
+	StatusMiss Status = "MISS"
+	// StatusHit means the response was served from the cache without requests.
+	StatusHit Status = "HIT"
+	// StatusRevalidated means the cached response was validated by the server with a conditional request.
+	StatusRevalidated Status = "REVALIDATED"
+	// StatusStale means the stale response was served because of stale-while-revalidate or stale-if-error.
+	StatusStale Status = "STALE"
+)
+
+// StatusOf returns the cache status of a given response.
+// It returns an empty string if the response did not pass through the cache.
+func StatusOf(resp *http.Response) Status {
+	if resp == nil {
+		return ""
+	}
+	return Status(resp.Header.Get(StatusHeader))
+}
+
+// Use creates an option that caches responses in a given storage.
+//  storage := cache.NewMemoryStorage(1000)
+//
+//  err := hx.Get(ctx, "https://api.example.com/config",
+//  	cache.Use(storage),
+//  	hx.WhenSuccess(hx.AsJSON(&cfg)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func Use(s Storage, opts ...Option) hx.Option {
+	return hx.TransportFrom(func(t http.RoundTripper) http.RoundTripper {
+		return NewTransport(t, s, opts...)
+	})
+}
+
+type Option func(*Transport)
+
+// Shared makes the cache behave as a shared cache.
+// A shared cache does not store responses with private directive, and it prefers s-maxage to max-age.
+func Shared() Option {
+	return func(t *Transport) { t.shared = true }
+}
+
+// DefaultMaxEntrySize is the default max size of response bodies stored in the cache.
+const DefaultMaxEntrySize = 1 << 20
+
+// MaxEntrySize sets the max size of response bodies stored in the cache. Larger responses are passed through without storing.
+// Bodies are read into memory up to this size before responses are returned. It is unlimited if n is zero or negative.
+func MaxEntrySize(n int64) Option {
+	return func(t *Transport) { t.maxEntrySize = n }
+}
+
+type Transport struct {
+	parent       http.RoundTripper
+	storage      Storage
+	shared       bool
+	maxEntrySize int64
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+func NewTransport(parent http.RoundTripper, s Storage, opts ...Option) *Transport {
+	t := &Transport{
+		parent:       parent,
+		storage:      s,
+		maxEntrySize: DefaultMaxEntrySize,
+	}
+	for _, f := range opts {
+		f(t)
+	}
+	return t
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := cacheKey(req)
+
+	if req.Method != http.MethodGet {
+		resp, err := t.next().RoundTrip(req)
+		if err == nil && !isSafeMethod(req.Method) && resp.StatusCode < 400 {
+			// https://tools.ietf.org/html/rfc7234#section-4.4
+			_ = t.storage.Delete(key)
+		}
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") {
+		return t.next().RoundTrip(req)
+	}
+
+	entry, _ := t.storage.Get(key)
+	if entry == nil {
+		return t.fetch(req, key)
+	}
+
+	cached, err := entry.response(req)
+	if err != nil || !entry.matchVary(req, cached) {
+		return t.fetch(req, key)
+	}
+
+	respCC := parseCacheControl(cached.Header)
+	age := currentAge(entry, cached.Header, now())
+	lifetime := freshnessLifetime(cached.Header, t.shared)
+	if maxAge, ok := reqCC.duration("max-age"); ok && maxAge < lifetime {
+		lifetime = maxAge
+	}
+	if minFresh, ok := reqCC.duration("min-fresh"); ok {
+		lifetime -= minFresh
+	}
+	mustRevalidate := reqCC.has("no-cache") || respCC.has("no-cache")
+
+	if !mustRevalidate && age < lifetime {
+		return serve(cached, StatusHit, age), nil
+	}
+
+	staleness := age - lifetime
+	if !mustRevalidate && !respCC.has("must-revalidate") {
+		if maxStale, ok := reqCC["max-stale"]; ok {
+			if d, ok := reqCC.duration("max-stale"); maxStale == "" || (ok && staleness <= d) {
+				return serve(cached, StatusStale, age), nil
+			}
+		}
+		if d, ok := respCC.duration("stale-while-revalidate"); ok && staleness <= d {
+			t.revalidateInBackground(req, key, entry)
+			return serve(cached, StatusStale, age), nil
+		}
+	}
+
+	resp, err := t.revalidate(req, key, entry)
+	if err != nil || resp.StatusCode >= 500 {
+		d, ok := respCC.duration("stale-if-error")
+		if rd, rok := reqCC.duration("stale-if-error"); rok {
+			d, ok = rd, true
+		}
+		if ok && staleness <= d && !respCC.has("must-revalidate") {
+			if resp != nil {
+				_, _ = io.Copy(ioutil.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			return serve(cached, StatusStale, age), nil
+		}
+	}
+	return resp, err
+}
+
+func (t *Transport) next() http.RoundTripper {
+	if t.parent == nil {
+		return http.DefaultTransport
+	}
+	return t.parent
+}
+
+// fetch sends a request and stores the response if it is cacheable.
+func (t *Transport) fetch(req *http.Request, key string) (*http.Response, error) {
+	reqTime := now()
+	resp, err := t.next().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respTime := now()
+
+	if isCacheable(req, resp, t.shared) {
+		t.store(req, resp, key, reqTime, respTime)
+	}
+
+	resp.Header.Set(StatusHeader, string(StatusMiss))
+	return resp, nil
+}
+
+// revalidate sends a conditional request with validators of the stored response.
+// https://tools.ietf.org/html/rfc7234#section-4.3
+func (t *Transport) revalidate(req *http.Request, key string, entry *Entry) (*http.Response, error) {
+	cached, err := entry.response(req)
+	if err != nil {
+		return t.fetch(req, key)
+	}
+
+	condReq := req.Clone(req.Context())
+	if etag := cached.Header.Get("ETag"); etag != "" && condReq.Header.Get("If-None-Match") == "" {
+		condReq.Header.Set("If-None-Match", etag)
+	}
+	if lm := cached.Header.Get("Last-Modified"); lm != "" && condReq.Header.Get("If-Modified-Since") == "" {
+		condReq.Header.Set("If-Modified-Since", lm)
+	}
+
+	reqTime := now()
+	resp, err := t.next().RoundTrip(condReq)
+	if err != nil {
+		return nil, err
+	}
+	respTime := now()
+
+	conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && !conditional:
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		// https://tools.ietf.org/html/rfc7234#section-4.3.4
+		for k, v := range resp.Header {
+			switch k {
+			case "Content-Length", "Transfer-Encoding", "Content-Encoding", "Content-Range":
+				continue
+			}
+			cached.Header[k] = v
+		}
+		t.store(req, cached, key, reqTime, respTime)
+		cached.Header.Set(StatusHeader, string(StatusRevalidated))
+		return cached, nil
+	case resp.StatusCode == http.StatusNotModified:
+		// the client sent its own validators
+	case isCacheable(req, resp, t.shared):
+		t.store(req, resp, key, reqTime, respTime)
+	case resp.StatusCode < 500:
+		_ = t.storage.Delete(key)
+	}
+
+	resp.Header.Set(StatusHeader, string(StatusMiss))
+	return resp, nil
+}
+
+// revalidating is a set of entries being revalidated in background.
+// It is shared by all transports since a transport is created for each request by Use.
+var revalidating sync.Map
+
+type revalidationKey struct {
+	// storage is the storage itself if it is comparable, or its type otherwise.
+	storage interface{}
+	key     string
+}
+
+// revalidateInBackground starts revalidation unless the same entry is already being revalidated.
+// Entries in storages of the same type are not distinguished if the storages are not comparable (e.g. structs holding maps).
+func (t *Transport) revalidateInBackground(req *http.Request, key string, entry *Entry) {
+	rk := revalidationKey{storage: t.storage, key: key}
+	if !isComparable(reflect.ValueOf(t.storage)) {
+		rk.storage = reflect.TypeOf(t.storage)
+	}
+	if _, loaded := revalidating.LoadOrStore(rk, struct{}{}); loaded {
+		return
+	}
+
+	bgReq := req.Clone(contextWithoutCancel(req.Context()))
+	go func() {
+		defer revalidating.Delete(rk)
+		resp, err := t.revalidate(bgReq, key, entry)
+		if err == nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}()
+}
+
+// isComparable reports whether a value can be used as a map key without panics.
+func isComparable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Interface:
+		return v.IsNil() || isComparable(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !isComparable(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !isComparable(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return v.Type().Comparable()
+	}
+}
+
+// store saves a response. The body of the response is replaced so that it can be read again.
+// Responses larger than maxEntrySize are not stored.
+func (t *Transport) store(req *http.Request, resp *http.Response, key string, reqTime, respTime time.Time) {
+	if !t.bufferBody(resp) {
+		return
+	}
+	e, err := newEntry(req, resp, reqTime, respTime)
+	if err != nil {
+		return
+	}
+	_ = t.storage.Set(key, e)
+}
+
+// bufferBody reads the response body into memory if it is not larger than maxEntrySize.
+// It reports whether the body has been buffered. Otherwise the body is restored so that it can be read from the beginning.
+func (t *Transport) bufferBody(resp *http.Response) bool {
+	if t.maxEntrySize <= 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return true
+	}
+	if resp.ContentLength > t.maxEntrySize {
+		return false
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, t.maxEntrySize+1))
+	if err != nil || int64(len(data)) > t.maxEntrySize {
+		resp.Body = &readCloser{Reader: io.MultiReader(bytes.NewReader(data), resp.Body), Closer: resp.Body}
+		return false
+	}
+	_ = resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return true
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func serve(resp *http.Response, st Status, age time.Duration) *http.Response {
+	resp.Header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	resp.Header.Set(StatusHeader, string(st))
+	return resp
+}
+
+// credentialHeaders are request headers that identify a user.
+// Responses to requests with different credentials are stored with different keys, so they are never served to other users.
+var credentialHeaders = []string{"Authorization", "Cookie"}
+
+func cacheKey(req *http.Request) string {
+	key := req.URL.String()
+
+	var h hash.Hash
+	for _, k := range credentialHeaders {
+		for _, v := range req.Header.Values(k) {
+			if h == nil {
+				h = sha256.New()
+			}
+			io.WriteString(h, k+": "+v+"\n")
+		}
+	}
+	if h != nil {
+		// credentials are hashed so that they are not stored in keys as they are
+		key += " " + hex.EncodeToString(h.Sum(nil))
+	}
+	return key
+}
+
+func isSafeMethod(meth string) bool {
+	switch meth {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func splitHeader(v string) []string {
+	var keys []string
+	for _, k := range strings.Split(v, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// detachedContext is a context that is never canceled, but it has values of the parent.
+// It is used for background revalidation.
+type detachedContext struct {
+	context.Context
+}
+
+func contextWithoutCancel(parent context.Context) context.Context {
+	return detachedContext{Context: parent}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+var now = time.Now
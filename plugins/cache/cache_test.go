@@ -0,0 +1,395 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/hx"
+	"github.com/izumin5210/hx/plugins/cache"
+)
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestTransport(t *testing.T) {
+	clk := &clock{now: time.Date(2019, time.December, 1, 12, 0, 0, 0, time.UTC)}
+	defer cache.SetNow(clk.Now)()
+
+	var (
+		reqCount int32
+		failing  int32
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqCount, 1)
+		w.Header().Set("Date", clk.Now().Format(http.TimeFormat))
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/max-age":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/expires":
+			w.Header().Set("Expires", clk.Now().Add(time.Minute).Format(http.TimeFormat))
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		case "/no-cache":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Last-Modified", "Sun, 01 Dec 2019 00:00:00 GMT")
+			if r.Header.Get("If-Modified-Since") != "" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+			w.Write([]byte(r.Header.Get("Accept-Language")))
+			return
+		case "/credentials":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Write([]byte(r.Header.Get("Authorization")))
+			return
+		case "/stale-while-revalidate":
+			w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=60")
+		case "/stale-if-error":
+			w.Header().Set("Cache-Control", "max-age=60, stale-if-error=60")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Write([]byte("hello"))
+		}
+	}))
+	defer ts.Close()
+
+	type result struct {
+		status cache.Status
+		code   int
+		body   string
+	}
+
+	do := func(t *testing.T, s cache.Storage, meth, path string, opts ...hx.Option) result {
+		t.Helper()
+		var (
+			res result
+			buf bytes.Buffer
+		)
+		opts = append(opts,
+			hx.HandleResponse(func(r *http.Response, err error) (*http.Response, error) {
+				if r != nil {
+					res.status = cache.StatusOf(r)
+					res.code = r.StatusCode
+				}
+				return r, err
+			}),
+			hx.HandleResponse(hx.AsBytesBuffer(&buf)),
+		)
+		err := hx.NewClient(cache.Use(s)).Request(context.Background(), meth, ts.URL+path, opts...)
+		if err != nil {
+			t.Fatalf("returned %v, want nil", err)
+		}
+		res.body = buf.String()
+		return res
+	}
+	get := func(t *testing.T, s cache.Storage, path string, opts ...hx.Option) result {
+		t.Helper()
+		return do(t, s, http.MethodGet, path, opts...)
+	}
+	check := func(t *testing.T, got result, want result, wantReqs int32) {
+		t.Helper()
+		if got != want {
+			t.Errorf("returned %v, want %v", got, want)
+		}
+		if got := atomic.LoadInt32(&reqCount); got != wantReqs {
+			t.Errorf("sent %d requests, want %d", got, wantReqs)
+		}
+	}
+
+	reset := func() {
+		atomic.StoreInt32(&reqCount, 0)
+		atomic.StoreInt32(&failing, 0)
+	}
+
+	t.Run("max-age", func(t *testing.T) {
+		defer reset()
+		s := cache.NewMemoryStorage(0)
+		check(t, get(t, s, "/max-age"), result{cache.StatusMiss, 200, "hello"}, 1)
+		clk.Advance(30 * time.Second)
+		check(t, get(t, s, "/max-age"), result{cache.StatusHit, 200, "hello"}, 1)
+		clk.Advance(31 * time.Second)
+		check(t, get(t, s, "/max-age"), result{cache.StatusRevalidated, 200, "hello"}, 2)
+		clk.Advance(30 * time.Second)
+		check(t, get(t, s, "/max-age"), result{cache.StatusHit, 200, "hello"}, 2)
+		check(t, get(t, s, "/max-age", hx.Header("Cache-Control", "no-cache")), result{cache.StatusRevalidated, 200, "hello"}, 3)
+		check(t, get(t, s, "/max-age", hx.Header("Cache-Control", "no-store")), result{"", 200, "hello"}, 4)
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		defer reset()
+		s := cache.NewMemoryStorage(0)
+		check(t, get(t, s, "/expires"), result{cache.StatusMiss, 200, "hello"}, 1)
+		clk.Advance(30 * time.Second)
+		check(t, get(t, s, "/expires"), result{cache.StatusHit, 200, "hello"}, 1)
+		clk.Advance(31 * time.Second)
+		check(t, get(t, s, "/expires"), result{cache.StatusMiss, 200, "hello"}, 2)
+	})
+
+	t.Run("no-store", func(t *testing.T) {
+		defer reset()
+		s := cache.NewMemoryStorage(0)
+		check(t, get(t, s, "/no-store"), result{cache.StatusMiss, 200, "hello"}, 1)
+		check(t, get(t, s, "/no-store"), result{cache.StatusMiss, 200, "hello"}, 2)
+	})
+
+	t.Run("no-cache", func(t *testing.T) {
+		defer reset()
+		s := cache.NewMemoryStorage(0)
+		check(t, get(t, s, "/no-cache"), result{cache.StatusMiss, 200, "hello"}, 1)
+		check(t, get(t, s, "/no-cache"), result{cache.StatusRevalidated, 200, "hello"}, 2)
+	})
+
+	t.Run("private", func(t *testing.T) {
+		defer reset()
+		s := cache.NewMemoryStorage(0)
+		check(t, get(t, s, "/private"), result{cache.StatusMiss, 200, "hello"}, 1)
+		check(t, get(t, s, "/private"), result{cache.StatusHit, 200, "hello"}, 1)
+
+		shared := cache.NewMemoryStorage(0)
+		opt := hx.TransportFrom(func(rt http.RoundTripper) http.RoundTripper {
+			return cache.NewTransport(rt, shared, cache.Shared())
+		})
+		check(t, get(t, cache.NewMemoryStorage(0), "/private", opt), result{cache.StatusMiss, 200, "hello"}, 2)
+		check(t, get(t, cache.NewMemoryStorage(0), "/private", opt), result{cache.StatusMiss, 200, "hello"}, 3)
+	})
+
+	t.Run("vary", func(t *testing.T) {
+		defer reset()
+		s := cache.NewMemoryStorage(0)
+		check(t, get(t, s, "/vary", hx.Header("Accept-Language", "ja")), result{cache.StatusMiss, 200, "ja"}, 1)
+		check(t, get(t, s, "/vary", hx.Header("Accept-Language", "ja")), result{cache.StatusHit, 200, "ja"}, 1)
+		check(t, get(t, s, "/vary", hx.Header("Accept-Language", "en")), result{cache.StatusMiss, 200, "en"}, 2)
+	})
+
+	t.Run("credentials", func(t *testing.T) {
+		defer reset()
+		s := cache.NewMemoryStorage(0)
+		check(t, get(t, s, "/credentials", hx.Bearer("alice")), result{cache.StatusMiss, 200, "Bearer alice"}, 1)
+		check(t, get(t, s, "/credentials", hx.Bearer("bob")), result{cache.StatusMiss, 200, "Bearer bob"}, 2)
+		check(t, get(t, s, "/credentials", hx.Bearer("alice")), result{cache.StatusHit, 200, "Bearer alice"}, 2)
+		check(t, get(t, s, "/credentials", hx.Bearer("bob")), result{cache.StatusHit, 200, "Bearer bob"}, 2)
+		check(t, get(t, s, "/credentials"), result{cache.StatusMiss, 200, ""}, 3)
+	})
+
+	t.Run("stale-while-revalidate", func(t *testing.T) {
+		defer reset()
+		s := cache.NewMemoryStorage(0)
+		check(t, get(t, s, "/stale-while-revalidate"), result{cache.StatusMiss, 200, "hello"}, 1)
+		clk.Advance(90 * time.Second)
+		if got, want := get(t, s, "/stale-while-revalidate").status, cache.StatusStale; got != want {
+			t.Errorf("returned %v, want %v", got, want)
+		}
+		for i := 0; i < 100 && atomic.LoadInt32(&reqCount) < 2; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cache.WaitRevalidation()
+		if got, want := atomic.LoadInt32(&reqCount), int32(2); got != want {
+			t.Errorf("sent %d requests, want %d", got, want)
+		}
+		clk.Advance(150 * time.Second)
+		check(t, get(t, s, "/stale-while-revalidate"), result{cache.StatusMiss, 200, "hello"}, 3)
+	})
+
+	t.Run("stale-if-error", func(t *testing.T) {
+		defer reset()
+		s := cache.NewMemoryStorage(0)
+		check(t, get(t, s, "/stale-if-error"), result{cache.StatusMiss, 200, "hello"}, 1)
+		atomic.StoreInt32(&failing, 1)
+		clk.Advance(90 * time.Second)
+		check(t, get(t, s, "/stale-if-error"), result{cache.StatusStale, 200, "hello"}, 2)
+		clk.Advance(60 * time.Second)
+		check(t, get(t, s, "/stale-if-error"), result{cache.StatusMiss, 503, ""}, 3)
+	})
+
+	t.Run("invalidation", func(t *testing.T) {
+		defer reset()
+		s := cache.NewMemoryStorage(0)
+		check(t, get(t, s, "/max-age"), result{cache.StatusMiss, 200, "hello"}, 1)
+		check(t, do(t, s, http.MethodPost, "/max-age"), result{"", 200, ""}, 2)
+		check(t, get(t, s, "/max-age"), result{cache.StatusMiss, 200, "hello"}, 3)
+	})
+
+	t.Run("disk", func(t *testing.T) {
+		defer reset()
+		s := cache.NewDiskStorage(t.TempDir())
+		check(t, get(t, s, "/max-age"), result{cache.StatusMiss, 200, "hello"}, 1)
+		check(t, get(t, s, "/max-age"), result{cache.StatusHit, 200, "hello"}, 1)
+	})
+}
+
+func TestTransport_MaxEntrySize(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+	var reqCount int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqCount, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		if r.URL.Path == "/chunked" {
+			// make the body chunked without Content-Length
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	for _, path := range []string{"/content-length", "/chunked"} {
+		t.Run(path, func(t *testing.T) {
+			atomic.StoreInt32(&reqCount, 0)
+			s := cache.NewMemoryStorage(0)
+
+			for _, tc := range []struct {
+				opts   []cache.Option
+				status cache.Status
+				reqs   int32
+			}{
+				{opts: []cache.Option{cache.MaxEntrySize(1024)}, status: cache.StatusMiss, reqs: 1},
+				{opts: []cache.Option{cache.MaxEntrySize(1024)}, status: cache.StatusMiss, reqs: 2},
+				{status: cache.StatusMiss, reqs: 3},
+				{status: cache.StatusHit, reqs: 3},
+			} {
+				var buf bytes.Buffer
+				err := hx.Get(context.Background(), ts.URL+path,
+					cache.Use(s, tc.opts...),
+					hx.HandleResponse(func(r *http.Response, err error) (*http.Response, error) {
+						if got := cache.StatusOf(r); got != tc.status {
+							t.Errorf("returned %v, want %v", got, tc.status)
+						}
+						return r, err
+					}),
+					hx.WhenSuccess(hx.AsBytesBuffer(&buf)),
+					hx.WhenFailure(hx.AsError()),
+				)
+				if err != nil {
+					t.Errorf("returned %v, want nil", err)
+				}
+				if got, want := buf.String(), body; got != want {
+					t.Errorf("returned %d bytes, want %d bytes", len(got), len(want))
+				}
+				if got, want := atomic.LoadInt32(&reqCount), tc.reqs; got != want {
+					t.Errorf("sent %d requests, want %d", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTransport_StaleWhileRevalidate(t *testing.T) {
+	cases := []struct {
+		test    string
+		storage cache.Storage
+	}{
+		{test: "pointer", storage: cache.NewMemoryStorage(0)},
+		{test: "not comparable", storage: nonComparableStorage{Storage: cache.NewMemoryStorage(0), tags: []string{"test"}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			clk := &clock{now: time.Date(2019, time.December, 1, 12, 0, 0, 0, time.UTC)}
+			defer cache.SetNow(clk.Now)()
+
+			var reqCount int32
+			unblock := make(chan struct{})
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&reqCount, 1) > 1 {
+					<-unblock
+				}
+				w.Header().Set("Date", clk.Now().Format(http.TimeFormat))
+				w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=60")
+				w.Write([]byte("hello"))
+			}))
+			defer ts.Close()
+
+			get := func() error {
+				return hx.Get(context.Background(), ts.URL, cache.Use(tc.storage), hx.WhenFailure(hx.AsError()))
+			}
+
+			if err := get(); err != nil {
+				t.Fatalf("returned %v, want nil", err)
+			}
+			clk.Advance(90 * time.Second)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := get(); err != nil {
+						t.Errorf("returned %v, want nil", err)
+					}
+				}()
+			}
+			wg.Wait()
+			defer cache.WaitRevalidation()
+			defer close(unblock)
+
+			// all stale responses have been served while the first revalidation is blocked
+			for i := 0; i < 100 && atomic.LoadInt32(&reqCount) < 2; i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if got, want := atomic.LoadInt32(&reqCount), int32(2); got != want {
+				t.Errorf("sent %d requests, want %d", got, want)
+			}
+		})
+	}
+}
+
+// nonComparableStorage panics when it is compared.
+type nonComparableStorage struct {
+	cache.Storage
+	tags []string
+}
+
+func TestMemoryStorage(t *testing.T) {
+	s := cache.NewMemoryStorage(2)
+	s.Set("a", &cache.Entry{})
+	s.Set("b", &cache.Entry{})
+	s.Get("a")
+	s.Set("c", &cache.Entry{})
+
+	if got, want := s.Len(), 2; got != want {
+		t.Errorf("stored %d entries, want %d", got, want)
+	}
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if e, _ := s.Get(key); (e != nil) != want {
+			t.Errorf("entry %q exists: %t, want %t", key, e != nil, want)
+		}
+	}
+}
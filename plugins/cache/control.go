@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheControl is parsed Cache-Control directives.
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.TrimSpace(d)
+			if d == "" {
+				continue
+			}
+			k, v := d, ""
+			if i := strings.IndexByte(d, '='); i >= 0 {
+				k, v = d[:i], strings.Trim(strings.TrimSpace(d[i+1:]), `"`)
+			}
+			cc[strings.ToLower(strings.TrimSpace(k))] = v
+		}
+	}
+	if _, ok := cc["no-cache"]; !ok && strings.EqualFold(h.Get("Pragma"), "no-cache") && h.Get("Cache-Control") == "" {
+		cc["no-cache"] = ""
+	}
+	return cc
+}
+
+func (cc cacheControl) has(k string) bool {
+	_, ok := cc[k]
+	return ok
+}
+
+func (cc cacheControl) duration(k string) (time.Duration, bool) {
+	v, ok := cc[k]
+	if !ok {
+		return 0, false
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || sec < 0 {
+		return 0, false
+	}
+	return time.Duration(sec) * time.Second, true
+}
+
+// cacheableStatus is a set of status codes that are cacheable by default.
+// https://tools.ietf.org/html/rfc7231#section-6.1
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// isCacheable reports whether a response can be stored.
+// https://tools.ietf.org/html/rfc7234#section-3
+func isCacheable(req *http.Request, resp *http.Response, shared bool) bool {
+	if req.Method != http.MethodGet || !cacheableStatus[resp.StatusCode] {
+		return false
+	}
+
+	reqCC, respCC := parseCacheControl(req.Header), parseCacheControl(resp.Header)
+	if reqCC.has("no-store") || respCC.has("no-store") {
+		return false
+	}
+	if shared && respCC.has("private") {
+		return false
+	}
+	if shared && req.Header.Get("Authorization") != "" &&
+		!respCC.has("public") && !respCC.has("s-maxage") && !respCC.has("must-revalidate") {
+		return false
+	}
+	if strings.TrimSpace(resp.Header.Get("Vary")) == "*" {
+		return false
+	}
+
+	return respCC.has("max-age") || (shared && respCC.has("s-maxage")) || respCC.has("public") ||
+		resp.Header.Get("Expires") != "" ||
+		resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// freshnessLifetime returns the duration the response is fresh.
+// https://tools.ietf.org/html/rfc7234#section-4.2.1
+func freshnessLifetime(h http.Header, shared bool) time.Duration {
+	cc := parseCacheControl(h)
+	if shared {
+		if d, ok := cc.duration("s-maxage"); ok {
+			return d
+		}
+	}
+	if d, ok := cc.duration("max-age"); ok {
+		return d
+	}
+
+	date, err := http.ParseTime(h.Get("Date"))
+	if err != nil {
+		return 0
+	}
+	if v := h.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0
+		}
+		if d := expires.Sub(date); d > 0 {
+			return d
+		}
+		return 0
+	}
+
+	// heuristic freshness
+	// https://tools.ietf.org/html/rfc7234#section-4.2.2
+	if lastModified, err := http.ParseTime(h.Get("Last-Modified")); err == nil {
+		if d := date.Sub(lastModified); d > 0 {
+			return d / 10
+		}
+	}
+
+	return 0
+}
+
+// currentAge returns the age of the stored response.
+// https://tools.ietf.org/html/rfc7234#section-4.2.3
+func currentAge(e *Entry, h http.Header, now time.Time) time.Duration {
+	var apparentAge time.Duration
+	if date, err := http.ParseTime(h.Get("Date")); err == nil {
+		if d := e.ResponseTime.Sub(date); d > 0 {
+			apparentAge = d
+		}
+	}
+
+	var ageValue time.Duration
+	if sec, err := strconv.ParseInt(h.Get("Age"), 10, 64); err == nil && sec > 0 {
+		ageValue = time.Duration(sec) * time.Second
+	}
+
+	correctedAge := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	if apparentAge > correctedAge {
+		correctedAge = apparentAge
+	}
+
+	return correctedAge + now.Sub(e.ResponseTime)
+}
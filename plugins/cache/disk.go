@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DiskStorage is a Storage that stores entries as files in a directory.
+type DiskStorage struct {
+	dir string
+}
+
+var _ Storage = (*DiskStorage)(nil)
+
+// NewDiskStorage creates a new DiskStorage that stores entries in a given directory.
+// The directory is created if it does not exist.
+func NewDiskStorage(dir string) *DiskStorage {
+	return &DiskStorage{dir: dir}
+}
+
+func (s *DiskStorage) Get(key string) (*Entry, error) {
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var e Entry
+	err = gob.NewDecoder(f).Decode(&e)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *DiskStorage) Set(key string, e *Entry) error {
+	err := os.MkdirAll(s.dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file and rename it to replace the entry atomically
+	f, err := os.CreateTemp(s.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = gob.NewEncoder(f).Encode(e)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), s.path(key))
+}
+
+func (s *DiskStorage) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *DiskStorage) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,21 @@
+package cache
+
+import "time"
+
+func SetNow(f func() time.Time) func() {
+	tmp := now
+	now = f
+	return func() { now = tmp }
+}
+
+// WaitRevalidation waits for background revalidations to finish.
+func WaitRevalidation() {
+	for {
+		n := 0
+		revalidating.Range(func(_, _ interface{}) bool { n++; return false })
+		if n == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
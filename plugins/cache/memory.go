@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// MemoryStorage is an in-memory Storage that evicts least recently used entries.
+type MemoryStorage struct {
+	size    int
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+var _ Storage = (*MemoryStorage)(nil)
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+}
+
+// NewMemoryStorage creates a new MemoryStorage that holds up to a given number of entries.
+// The number of entries is unlimited if size is zero.
+func NewMemoryStorage(size int) *MemoryStorage {
+	return &MemoryStorage{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (s *MemoryStorage) Get(key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.ll.MoveToFront(el)
+		return el.Value.(*memoryItem).entry, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStorage) Set(key string, e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.ll.MoveToFront(el)
+		el.Value.(*memoryItem).entry = e
+		return nil
+	}
+
+	s.entries[key] = s.ll.PushFront(&memoryItem{key: key, entry: e})
+
+	for s.size > 0 && s.ll.Len() > s.size {
+		el := s.ll.Back()
+		s.ll.Remove(el)
+		delete(s.entries, el.Value.(*memoryItem).key)
+	}
+
+	return nil
+}
+
+func (s *MemoryStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.ll.Remove(el)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// Len returns the number of stored entries.
+func (s *MemoryStorage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
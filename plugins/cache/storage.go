@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+// Storage stores cached responses.
+// Implementations must be safe for concurrent use.
+type Storage interface {
+	// Get returns a stored entry. It returns nil if no entries are found.
+	Get(key string) (*Entry, error)
+	Set(key string, e *Entry) error
+	Delete(key string) error
+}
+
+// Entry is a stored response.
+type Entry struct {
+	// Response is a response serialized in HTTP/1.x wire format.
+	Response []byte
+	// RequestHeader is the header of the request that the response was received for.
+	// It is used to check the Vary header.
+	RequestHeader http.Header
+	RequestTime   time.Time
+	ResponseTime  time.Time
+}
+
+func newEntry(req *http.Request, resp *http.Response, reqTime, respTime time.Time) (*Entry, error) {
+	data, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{
+		Response:      data,
+		RequestHeader: req.Header.Clone(),
+		RequestTime:   reqTime,
+		ResponseTime:  respTime,
+	}, nil
+}
+
+func (e *Entry) response(req *http.Request) (*http.Response, error) {
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(e.Response)), req)
+}
+
+// matchVary reports whether a given request has the same values with the stored request for headers specified by Vary.
+func (e *Entry) matchVary(req *http.Request, resp *http.Response) bool {
+	for _, v := range resp.Header.Values("Vary") {
+		for _, k := range splitHeader(v) {
+			if k == "*" {
+				return false
+			}
+			if req.Header.Get(k) != e.RequestHeader.Get(k) {
+				return false
+			}
+		}
+	}
+	return true
+}
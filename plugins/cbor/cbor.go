@@ -0,0 +1,107 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	fxcbor "github.com/fxamacker/cbor/v2"
+	"github.com/izumin5210/hx"
+)
+
+// ContentType is a media type of CBOR set to requests.
+// Media types with +cbor suffix are also decoded by hx.AsAuto.
+const ContentType = "application/cbor"
+
+var (
+	DefaultConfig = &Config{}
+
+	contentTypeCBOR = hx.Header("Content-Type", ContentType)
+)
+
+func init() {
+	hx.RegisterDecoder(ContentType, func(r io.Reader, v interface{}) error { return DefaultConfig.decode(r, v) })
+}
+
+// CBOR sets data to request body as CBOR.
+// This will marshal a given data with cbor.Marshal in default.
+func CBOR(v interface{}) hx.Option {
+	return DefaultConfig.CBOR(v)
+}
+
+// AsCBOR is hx.ResponseHandler for unmarshaling response bodies as CBOR.
+// This will unmarshal a received data with cbor.Decoder in default.
+func AsCBOR(dst interface{}) hx.ResponseHandler {
+	return DefaultConfig.AsCBOR(dst)
+}
+
+type Config struct {
+	EncodeFunc func(interface{}) (io.Reader, error)
+	DecodeFunc func(io.Reader, interface{}) error
+}
+
+func (c *Config) CBOR(v interface{}) hx.Option {
+	return hx.OptionFunc(func(hc *hx.Config) error {
+		r, err := c.encode(v)
+		if err != nil {
+			return err
+		}
+		hc.Body = r
+		hc.GetBody = nil
+		return contentTypeCBOR.ApplyOption(hc)
+	})
+}
+
+func (c *Config) AsCBOR(dst interface{}) hx.ResponseHandler {
+	return func(r *http.Response, err error) (*http.Response, error) {
+		if r == nil || err != nil {
+			return r, err
+		}
+		if !hasBody(r) {
+			return r, nil
+		}
+
+		defer r.Body.Close()
+		err = c.decode(r.Body, dst)
+		if err != nil {
+			return nil, &hx.ResponseError{Response: r, Err: err}
+		}
+		return r, nil
+	}
+}
+
+func (c *Config) encode(v interface{}) (io.Reader, error) {
+	if f := c.EncodeFunc; f != nil {
+		return f(v)
+	}
+
+	data, err := fxcbor.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (c *Config) decode(r io.Reader, v interface{}) error {
+	if f := c.DecodeFunc; f != nil {
+		return f(r, v)
+	}
+
+	return fxcbor.NewDecoder(r).Decode(v)
+}
+
+// hasBody reports whether a given response can have a body, in the same way as response handlers of hx.
+// Responses to HEAD requests, and responses with 1xx, 204 or 304 status never have a body.
+func hasBody(r *http.Response) bool {
+	if r.Body == nil {
+		return false
+	}
+	if r.Request != nil && r.Request.Method == http.MethodHead {
+		return false
+	}
+	switch {
+	case r.StatusCode/100 == 1, r.StatusCode == http.StatusNoContent, r.StatusCode == http.StatusNotModified:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,2 @@
+// A plugin for marshaling and unmarshaling CBOR (RFC 8949).
+package cbor
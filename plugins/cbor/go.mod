@@ -0,0 +1,12 @@
+module github.com/izumin5210/hx/plugins/cbor
+
+go 1.18
+
+replace github.com/izumin5210/hx => ../../
+
+require (
+	github.com/fxamacker/cbor/v2 v2.4.0
+	github.com/izumin5210/hx v0.3.0
+)
+
+require github.com/x448/float16 v0.8.4 // indirect
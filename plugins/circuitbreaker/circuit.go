@@ -0,0 +1,117 @@
+package circuitbreaker
+
+import "time"
+
+// circuit is a state machine of a circuit. It is not goroutine-safe.
+type circuit struct {
+	cfg   *config
+	state State
+	// generation is incremented on every state transition to ignore results of requests allowed in previous states.
+	generation uint64
+	expiry     time.Time
+
+	requests            int
+	failures            int
+	consecutiveFailures int
+	inFlight            int
+	successes           int
+}
+
+func newCircuit(cfg *config, now time.Time) *circuit {
+	c := &circuit{cfg: cfg}
+	c.reset(now)
+	return c
+}
+
+// tick changes the state by elapsed time.
+func (c *circuit) tick(now time.Time) {
+	switch c.state {
+	case StateOpen:
+		if !now.Before(c.expiry) {
+			c.setState(StateHalfOpen, now)
+		}
+	case StateClosed:
+		if !c.expiry.IsZero() && !now.Before(c.expiry) {
+			c.reset(now)
+		}
+	}
+}
+
+func (c *circuit) allow(now time.Time) (uint64, bool) {
+	c.tick(now)
+
+	switch c.state {
+	case StateOpen:
+		return 0, false
+	case StateHalfOpen:
+		if c.inFlight+c.successes >= c.cfg.halfOpenRequests {
+			return 0, false
+		}
+		c.inFlight++
+	}
+	return c.generation, true
+}
+
+func (c *circuit) report(gen uint64, failure bool, now time.Time) {
+	if gen != c.generation {
+		return
+	}
+
+	switch c.state {
+	case StateClosed:
+		c.requests++
+		if failure {
+			c.failures++
+			c.consecutiveFailures++
+		} else {
+			c.consecutiveFailures = 0
+		}
+		if c.shouldTrip() {
+			c.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		c.inFlight--
+		if failure {
+			c.setState(StateOpen, now)
+			return
+		}
+		c.successes++
+		if c.successes >= c.cfg.halfOpenRequests {
+			c.setState(StateClosed, now)
+		}
+	}
+}
+
+func (c *circuit) shouldTrip() bool {
+	if n := c.cfg.consecutiveFailures; n > 0 && c.consecutiveFailures >= n {
+		return true
+	}
+	if r := c.cfg.failureRatio; r > 0 && c.requests >= c.cfg.minRequests {
+		return float64(c.failures)/float64(c.requests) >= r
+	}
+	return false
+}
+
+func (c *circuit) setState(s State, now time.Time) {
+	c.state = s
+	c.generation++
+	c.reset(now)
+}
+
+func (c *circuit) reset(now time.Time) {
+	c.requests, c.failures, c.consecutiveFailures = 0, 0, 0
+	c.inFlight, c.successes = 0, 0
+
+	switch c.state {
+	case StateOpen:
+		c.expiry = now.Add(c.cfg.openTimeout)
+	case StateClosed:
+		if c.cfg.interval > 0 {
+			c.expiry = now.Add(c.cfg.interval)
+		} else {
+			c.expiry = time.Time{}
+		}
+	default:
+		c.expiry = time.Time{}
+	}
+}
@@ -0,0 +1,177 @@
+// A plugin for stopping requests to failing servers with circuit breakers.
+package circuitbreaker
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/hx"
+)
+
+var now = time.Now
+
+// State is a state of circuits.
+type State int
+
+const (
+	// StateClosed means requests are sent as usual.
+	StateClosed State = iota
+	// StateOpen means requests are rejected without being sent.
+	StateOpen
+	// StateHalfOpen means a limited number of requests are sent to check whether the server has recovered.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// OpenError is returned when a request is rejected by an open (or half-open) circuit.
+//  var openErr *circuitbreaker.OpenError
+//  if errors.As(err, &openErr) {
+//  	// fall back...
+//  }
+type OpenError struct {
+	Key   string
+	State State
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuitbreaker: circuit %q is %s", e.Key, e.State)
+}
+
+// CircuitBreaker holds circuits for each key. It should be shared between requests.
+type CircuitBreaker struct {
+	cond hx.ResponseHandlerCond
+	cfg  *config
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// New creates a CircuitBreaker. A given condition decides whether a response is a failure or not.
+// It opens a circuit after 5 consecutive failures in default.
+//  cb := circuitbreaker.New(hx.Any(hx.IsServerError, hx.IsTemporaryError),
+//  	circuitbreaker.FailureRatio(0.5, 20),
+//  	circuitbreaker.OpenTimeout(30*time.Second),
+//  	circuitbreaker.OnStateChange(func(key string, from, to circuitbreaker.State) {
+//  		log.Printf("circuit %s: %s -> %s", key, from, to)
+//  	}),
+//  )
+//
+//  err := hx.Get(ctx, "https://api.example.com/contents/1",
+//  	circuitbreaker.Use(cb),
+//  	hx.WhenSuccess(hx.AsJSON(&cont)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func New(cond hx.ResponseHandlerCond, opts ...Option) *CircuitBreaker {
+	return &CircuitBreaker{
+		cond:     cond,
+		cfg:      newConfig(opts),
+		circuits: map[string]*circuit{},
+	}
+}
+
+// Use creates an option that sends requests through a given circuit breaker.
+func Use(cb *CircuitBreaker) hx.Option {
+	return hx.TransportFrom(cb.Wrap)
+}
+
+// Wrap returns a http.RoundTripper that sends requests through the circuit breaker.
+func (cb *CircuitBreaker) Wrap(parent http.RoundTripper) http.RoundTripper {
+	return &Transport{parent: parent, cb: cb}
+}
+
+// State returns the current state of a circuit for a given key.
+func (cb *CircuitBreaker) State(key string) State {
+	cb.mu.Lock()
+	c, ok := cb.circuits[key]
+	if !ok {
+		cb.mu.Unlock()
+		return StateClosed
+	}
+	from := c.state
+	c.tick(now())
+	to := c.state
+	cb.mu.Unlock()
+
+	cb.notify(key, from, to)
+	return to
+}
+
+func (cb *CircuitBreaker) allow(key string) (uint64, error) {
+	cb.mu.Lock()
+	c, ok := cb.circuits[key]
+	if !ok {
+		c = newCircuit(cb.cfg, now())
+		cb.circuits[key] = c
+	}
+	from := c.state
+	gen, ok := c.allow(now())
+	to := c.state
+	cb.mu.Unlock()
+
+	cb.notify(key, from, to)
+	if !ok {
+		return 0, &OpenError{Key: key, State: to}
+	}
+	return gen, nil
+}
+
+func (cb *CircuitBreaker) report(key string, gen uint64, failure bool) {
+	cb.mu.Lock()
+	c := cb.circuits[key]
+	from := c.state
+	c.report(gen, failure, now())
+	to := c.state
+	cb.mu.Unlock()
+
+	cb.notify(key, from, to)
+}
+
+func (cb *CircuitBreaker) notify(key string, from, to State) {
+	if from != to && cb.cfg.onStateChange != nil {
+		cb.cfg.onStateChange(key, from, to)
+	}
+}
+
+// Transport is a http.RoundTripper that rejects requests while circuits are open.
+type Transport struct {
+	parent http.RoundTripper
+	cb     *CircuitBreaker
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := t.cb.cfg.keyFunc(req)
+
+	gen, err := t.cb.allow(key)
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	next := t.parent
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	t.cb.report(key, gen, t.cb.cond(resp, err))
+
+	return resp, err
+}
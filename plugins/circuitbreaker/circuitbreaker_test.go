@@ -0,0 +1,208 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/hx"
+	"github.com/izumin5210/hx/plugins/circuitbreaker"
+)
+
+type transition struct {
+	key      string
+	from, to circuitbreaker.State
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		healthy bool
+		cnt     int
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		cnt++
+		if healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	setHealthy := func(v bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		healthy = v
+	}
+	resetCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := cnt
+		cnt = 0
+		return n
+	}
+
+	current := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer circuitbreaker.SetNow(func() time.Time { return current })()
+
+	var transitions []transition
+	cb := circuitbreaker.New(hx.IsServerError,
+		circuitbreaker.ConsecutiveFailures(3),
+		circuitbreaker.OpenTimeout(time.Minute),
+		circuitbreaker.OnStateChange(func(key string, from, to circuitbreaker.State) {
+			transitions = append(transitions, transition{key: key, from: from, to: to})
+		}),
+	)
+	key := ts.Listener.Addr().String()
+
+	get := func() error {
+		return hx.Get(context.Background(), ts.URL, circuitbreaker.Use(cb), hx.WhenFailure(hx.AsError()))
+	}
+
+	// closed -> open
+	for i := 0; i < 5; i++ {
+		_ = get()
+	}
+	if got, want := resetCount(), 3; got != want {
+		t.Errorf("sent %d requests, want %d", got, want)
+	}
+	if got, want := cb.State(key), circuitbreaker.StateOpen; got != want {
+		t.Errorf("State() returned %v, want %v", got, want)
+	}
+
+	err := get()
+	var openErr *circuitbreaker.OpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("returned %v, want *circuitbreaker.OpenError", err)
+	}
+	if got, want := openErr.Key, key; got != want {
+		t.Errorf("OpenError.Key is %q, want %q", got, want)
+	}
+
+	// open -> half-open -> open
+	current = current.Add(time.Minute)
+	if err := get(); err == nil {
+		t.Error("returned nil, want an error")
+	}
+	if got, want := resetCount(), 1; got != want {
+		t.Errorf("sent %d requests, want %d", got, want)
+	}
+	if got, want := cb.State(key), circuitbreaker.StateOpen; got != want {
+		t.Errorf("State() returned %v, want %v", got, want)
+	}
+
+	// open -> half-open -> closed
+	setHealthy(true)
+	current = current.Add(time.Minute)
+	if err := get(); err != nil {
+		t.Errorf("returned %v, want nil", err)
+	}
+	if got, want := cb.State(key), circuitbreaker.StateClosed; got != want {
+		t.Errorf("State() returned %v, want %v", got, want)
+	}
+
+	want := []transition{
+		{key: key, from: circuitbreaker.StateClosed, to: circuitbreaker.StateOpen},
+		{key: key, from: circuitbreaker.StateOpen, to: circuitbreaker.StateHalfOpen},
+		{key: key, from: circuitbreaker.StateHalfOpen, to: circuitbreaker.StateOpen},
+		{key: key, from: circuitbreaker.StateOpen, to: circuitbreaker.StateHalfOpen},
+		{key: key, from: circuitbreaker.StateHalfOpen, to: circuitbreaker.StateClosed},
+	}
+	if !reflect.DeepEqual(transitions, want) {
+		t.Errorf("transitions are %v, want %v", transitions, want)
+	}
+}
+
+func TestCircuitBreaker_FailureRatio(t *testing.T) {
+	var cnt int
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		cnt++
+		code := http.StatusOK
+		if req.URL.Path == "/fail" {
+			code = http.StatusInternalServerError
+		}
+		return &http.Response{StatusCode: code, Body: http.NoBody, Request: req}, nil
+	})
+
+	cb := circuitbreaker.New(hx.IsServerError,
+		circuitbreaker.FailureRatio(0.5, 4),
+		circuitbreaker.KeyFunc(func(r *http.Request) string { return r.URL.Path }),
+	)
+
+	get := func(path string) error {
+		return hx.Get(context.Background(), "https://api.example.com"+path, hx.Transport(rt), circuitbreaker.Use(cb))
+	}
+
+	for _, path := range []string{"/ok", "/ok", "/fail", "/ok", "/ok"} {
+		_ = get(path)
+	}
+	for _, path := range []string{"/fail", "/fail", "/fail", "/fail"} {
+		_ = get(path)
+	}
+
+	if got, want := cb.State("/ok"), circuitbreaker.StateClosed; got != want {
+		t.Errorf("State(/ok) returned %v, want %v", got, want)
+	}
+	if got, want := cb.State("/fail"), circuitbreaker.StateOpen; got != want {
+		t.Errorf("State(/fail) returned %v, want %v", got, want)
+	}
+
+	cnt = 0
+	var openErr *circuitbreaker.OpenError
+	if err := get("/fail"); !errors.As(err, &openErr) {
+		t.Errorf("returned %v, want *circuitbreaker.OpenError", err)
+	}
+	if err := get("/ok"); err != nil {
+		t.Errorf("returned %v, want nil", err)
+	}
+	if got, want := cnt, 1; got != want {
+		t.Errorf("sent %d requests, want %d", got, want)
+	}
+}
+
+func TestCircuitBreaker_FailureRatioInterval(t *testing.T) {
+	var failing bool
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		code := http.StatusOK
+		if failing {
+			code = http.StatusInternalServerError
+		}
+		return &http.Response{StatusCode: code, Body: http.NoBody, Request: req}, nil
+	})
+
+	current := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer circuitbreaker.SetNow(func() time.Time { return current })()
+
+	cb := circuitbreaker.New(hx.IsServerError, circuitbreaker.FailureRatio(0.5, 4))
+
+	get := func() error {
+		return hx.Get(context.Background(), "https://api.example.com/", hx.Transport(rt), circuitbreaker.Use(cb))
+	}
+
+	// a long healthy period
+	for i := 0; i < 100; i++ {
+		_ = get()
+	}
+
+	current = current.Add(circuitbreaker.DefaultInterval)
+	failing = true
+	for i := 0; i < 4; i++ {
+		_ = get()
+	}
+
+	if got, want := cb.State("api.example.com"), circuitbreaker.StateOpen; got != want {
+		t.Errorf("State returned %v, want %v", got, want)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,9 @@
+package circuitbreaker
+
+import "time"
+
+func SetNow(f func() time.Time) func() {
+	tmp := now
+	now = f
+	return func() { now = tmp }
+}
@@ -0,0 +1,7 @@
+module github.com/izumin5210/hx/plugins/circuitbreaker
+
+go 1.18
+
+replace github.com/izumin5210/hx => ../../
+
+require github.com/izumin5210/hx v0.3.0
@@ -0,0 +1,86 @@
+package circuitbreaker
+
+import (
+	"net/http"
+	"time"
+)
+
+type Option func(*config)
+
+// DefaultInterval is the interval to reset counts used with FailureRatio when Interval is not specified.
+const DefaultInterval = 60 * time.Second
+
+type config struct {
+	consecutiveFailures int
+	failureRatio        float64
+	minRequests         int
+	interval            time.Duration
+	openTimeout         time.Duration
+	halfOpenRequests    int
+	keyFunc             func(*http.Request) string
+	onStateChange       func(key string, from, to State)
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		openTimeout:      60 * time.Second,
+		halfOpenRequests: 1,
+		keyFunc:          func(r *http.Request) string { return r.URL.Host },
+	}
+	for _, f := range opts {
+		f(c)
+	}
+	if c.consecutiveFailures <= 0 && c.failureRatio <= 0 {
+		c.consecutiveFailures = 5
+	}
+	// otherwise the ratio would be of all requests since the circuit was closed
+	if c.failureRatio > 0 && c.interval <= 0 {
+		c.interval = DefaultInterval
+	}
+	if c.halfOpenRequests <= 0 {
+		c.halfOpenRequests = 1
+	}
+	return c
+}
+
+// ConsecutiveFailures opens a circuit after n consecutive failures.
+func ConsecutiveFailures(n int) Option {
+	return func(c *config) { c.consecutiveFailures = n }
+}
+
+// FailureRatio opens a circuit when the ratio of failures reaches a given ratio.
+// It is evaluated only after minRequests requests are counted.
+// The counts are reset every Interval, which is DefaultInterval if not specified.
+func FailureRatio(ratio float64, minRequests int) Option {
+	return func(c *config) {
+		c.failureRatio = ratio
+		c.minRequests = minRequests
+	}
+}
+
+// Interval resets counts of requests and failures periodically while circuits are closed.
+// The counts are not reset if d <= 0, which is the default, unless FailureRatio is specified.
+func Interval(d time.Duration) Option {
+	return func(c *config) { c.interval = d }
+}
+
+// OpenTimeout is the duration of the open state, after which the circuit becomes half-open. It is 60 seconds in default.
+func OpenTimeout(d time.Duration) Option {
+	return func(c *config) { c.openTimeout = d }
+}
+
+// HalfOpenRequests is the number of requests allowed in the half-open state.
+// The circuit is closed when all of them succeed. It is 1 in default.
+func HalfOpenRequests(n int) Option {
+	return func(c *config) { c.halfOpenRequests = n }
+}
+
+// KeyFunc changes how requests are grouped into circuits. Requests are grouped per host in default.
+func KeyFunc(f func(*http.Request) string) Option {
+	return func(c *config) { c.keyFunc = f }
+}
+
+// OnStateChange registers a callback called on state transitions, e.g. for metrics.
+func OnStateChange(f func(key string, from, to State)) Option {
+	return func(c *config) { c.onStateChange = f }
+}
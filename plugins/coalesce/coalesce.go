@@ -0,0 +1,219 @@
+// A plugin for coalescing identical in-flight requests.
+package coalesce
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/hx"
+)
+
+// DefaultHeaders are request headers used to identify requests in default.
+var DefaultHeaders = []string{"Accept", "Authorization", "Cookie"}
+
+type Option func(*Group)
+
+// Headers changes request headers used to identify requests in addition to methods and URLs.
+func Headers(keys ...string) Option {
+	return func(g *Group) { g.headers = keys }
+}
+
+// Group coalesces identical in-flight GET and HEAD requests, so that only one of them is sent.
+// Responses are buffered in memory, and each caller receives an independent copy.
+// It should be shared between requests.
+type Group struct {
+	headers []string
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+var _ hx.Interceptor = (*Group)(nil)
+
+// New creates a Group.
+//  g := coalesce.New()
+//
+//  // in many goroutines
+//  err := hx.Get(ctx, "https://api.example.com/config",
+//  	coalesce.Use(g),
+//  	hx.WhenSuccess(hx.AsJSON(&cfg)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func New(opts ...Option) *Group {
+	g := &Group{headers: DefaultHeaders, calls: map[string]*call{}}
+	for _, f := range opts {
+		f(g)
+	}
+	return g
+}
+
+// Use creates an option that coalesces requests with a given group.
+func Use(g *Group) hx.Option {
+	return hx.Intercept(g)
+}
+
+// DoRequest implements hx.Interceptor.
+func (g *Group) DoRequest(cli *http.Client, req *http.Request, next hx.RequestFunc) (*http.Response, error) {
+	if !isCoalescable(req) {
+		return next(cli, req)
+	}
+
+	newCli := *cli
+	newCli.Transport = &transport{parent: cli.Transport, g: g}
+	return next(&newCli, req)
+}
+
+func (g *Group) Wrap(f hx.RequestFunc) hx.RequestFunc {
+	return func(c *http.Client, r *http.Request) (*http.Response, error) { return g.DoRequest(c, r, f) }
+}
+
+func (g *Group) key(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteString(" ")
+	b.WriteString(req.URL.String())
+	for _, k := range g.headers {
+		b.WriteString("\n")
+		b.WriteString(k)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(req.Header.Values(k), ", "))
+	}
+	return b.String()
+}
+
+// call is an in-flight request shared between callers.
+type call struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+// join returns an in-flight call for a request, or starts a new one.
+func (g *Group) join(parent http.RoundTripper, req *http.Request) *call {
+	key := g.key(req)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c, ok := g.calls[key]; ok {
+		c.waiters++
+		return c
+	}
+
+	// the shared request is not canceled by the first caller, but it is canceled when all callers leave.
+	ctx, cancel := context.WithCancel(contextWithoutCancel(req.Context()))
+	c := &call{done: make(chan struct{}), cancel: cancel, waiters: 1}
+	g.calls[key] = c
+
+	go func() {
+		defer cancel()
+		c.resp, c.body, c.err = roundTrip(parent, req.WithContext(ctx))
+
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	return c
+}
+
+// leave removes a caller from a call. The call is canceled when no callers remain.
+func (g *Group) leave(req *http.Request, c *call) {
+	key := g.key(req)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c.waiters--
+	if c.waiters > 0 {
+		return
+	}
+	c.cancel()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+func roundTrip(rt http.RoundTripper, req *http.Request) (*http.Response, []byte, error) {
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+type transport struct {
+	parent http.RoundTripper
+	g      *Group
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.parent
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if !isCoalescable(req) {
+		return next.RoundTrip(req)
+	}
+
+	c := t.g.join(next, req)
+
+	select {
+	case <-req.Context().Done():
+		t.g.leave(req, c)
+		return nil, req.Context().Err()
+	case <-c.done:
+	}
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	resp := *c.resp
+	resp.Header = c.resp.Header.Clone()
+	resp.Trailer = c.resp.Trailer.Clone()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(c.body))
+	resp.ContentLength = int64(len(c.body))
+	resp.Request = req
+	return &resp, nil
+}
+
+func isCoalescable(req *http.Request) bool {
+	if req.Method != "" && req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+// detachedContext is a context that is never canceled, but it has values of the parent.
+type detachedContext struct {
+	context.Context
+}
+
+func contextWithoutCancel(parent context.Context) context.Context {
+	return detachedContext{Context: parent}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
@@ -0,0 +1,150 @@
+package coalesce_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/hx"
+	"github.com/izumin5210/hx/plugins/coalesce"
+)
+
+func TestGroup(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+
+	var (
+		cnt       int32
+		release   = make(chan struct{})
+		cancelled = make(chan struct{}, 1)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&cnt, 1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"` + r.Header.Get("Authorization") + `"}`))
+	}))
+	defer ts.Close()
+
+	g := coalesce.New()
+
+	get := func(ctx context.Context, token string) (*Config, error) {
+		var cfg Config
+		err := hx.Get(ctx, ts.URL+"/config",
+			hx.Header("Authorization", token),
+			coalesce.Use(g),
+			hx.WhenSuccess(hx.AsJSON(&cfg)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		return &cfg, err
+	}
+
+	waitRequests := func(n int32) {
+		for atomic.LoadInt32(&cnt) < n {
+			time.Sleep(time.Millisecond)
+		}
+		// wait for other callers joining
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Run("coalesced", func(t *testing.T) {
+		atomic.StoreInt32(&cnt, 0)
+		release = make(chan struct{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			token := "foo"
+			if i%2 == 1 {
+				token = "bar"
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cfg, err := get(context.Background(), token)
+				if err != nil {
+					t.Errorf("returned %v, want nil", err)
+				}
+				if got, want := cfg.Name, token; got != want {
+					t.Errorf("received %q, want %q", got, want)
+				}
+			}()
+		}
+
+		waitRequests(2)
+		close(release)
+		wg.Wait()
+
+		if got, want := atomic.LoadInt32(&cnt), int32(2); got != want {
+			t.Errorf("sent %d requests, want %d", got, want)
+		}
+	})
+
+	t.Run("a caller is cancelled", func(t *testing.T) {
+		atomic.StoreInt32(&cnt, 0)
+		release = make(chan struct{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 2)
+		go func() {
+			_, err := get(ctx, "foo")
+			errCh <- err
+		}()
+		go func() {
+			cfg, err := get(context.Background(), "foo")
+			if err == nil && cfg.Name != "foo" {
+				err = errors.New("unexpected response: " + cfg.Name)
+			}
+			errCh <- err
+		}()
+
+		waitRequests(1)
+		cancel()
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("returned %v, want %v", err, context.Canceled)
+		}
+		close(release)
+		if err := <-errCh; err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+	})
+
+	t.Run("all callers are cancelled", func(t *testing.T) {
+		atomic.StoreInt32(&cnt, 0)
+		release = make(chan struct{})
+		defer close(release)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := get(ctx, "foo")
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("returned %v, want %v", err, context.Canceled)
+				}
+			}()
+		}
+
+		waitRequests(1)
+		cancel()
+		wg.Wait()
+
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Error("the shared request is not cancelled")
+		}
+	})
+}
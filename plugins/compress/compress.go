@@ -0,0 +1,266 @@
+// A plugin for compressing request bodies and decompressing response bodies.
+package compress
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/izumin5210/hx"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings supported by this package.
+const (
+	Brotli  = "br"
+	Zstd    = "zstd"
+	Gzip    = "gzip"
+	Deflate = "deflate"
+)
+
+// DefaultAcceptEncoding is a value of Accept-Encoding header sent by Decompress.
+var DefaultAcceptEncoding = strings.Join([]string{Brotli, Zstd, Gzip, Deflate}, ", ")
+
+// Decompress creates an option that decodes br, zstd, gzip and deflate response bodies.
+// It sets Accept-Encoding header to DefaultAcceptEncoding unless the header is given.
+// Content-Encoding and Content-Length headers are removed from decoded responses.
+//  err := hx.Get(ctx, "https://api.example.com/contents/1",
+//  	compress.Decompress(),
+//  	hx.WhenSuccess(hx.AsJSON(&cont)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func Decompress() hx.Option {
+	return hx.TransportFrom(NewTransport)
+}
+
+// CompressBody creates an option that compresses request bodies with a given content coding,
+// and sets Content-Encoding header.
+//  err := hx.Post(ctx, "https://ingest.example.com/events",
+//  	hx.JSON(events),
+//  	compress.CompressBody(compress.Gzip),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func CompressBody(encoding string) hx.Option {
+	return hx.HandleRequest(func(r *http.Request) (*http.Request, error) {
+		if !isSupported(encoding) {
+			return nil, fmt.Errorf("compress: unsupported content coding %q", encoding)
+		}
+		if r.Body == nil || r.Body == http.NoBody {
+			return r, nil
+		}
+
+		r.Body = compressBody(encoding, r.Body)
+		r.ContentLength = -1
+		r.Header.Set("Content-Encoding", encoding)
+
+		if getBody := r.GetBody; getBody != nil {
+			r.GetBody = func() (io.ReadCloser, error) {
+				body, err := getBody()
+				if err != nil {
+					return nil, err
+				}
+				return compressBody(encoding, body), nil
+			}
+		}
+
+		return r, nil
+	})
+}
+
+// compressBody compresses a body in background through io.Pipe.
+func compressBody(encoding string, body io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer body.Close()
+		w, err := newEncoder(encoding, pw)
+		if err == nil {
+			_, err = io.Copy(w, body)
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+func newEncoder(encoding string, w io.Writer) (io.WriteCloser, error) {
+	switch encoding {
+	case Brotli:
+		return brotli.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Deflate:
+		return zlib.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("compress: unsupported content coding %q", encoding)
+	}
+}
+
+// Transport is a http.RoundTripper that decodes response bodies.
+type Transport struct {
+	parent http.RoundTripper
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+func NewTransport(parent http.RoundTripper) http.RoundTripper {
+	return &Transport{parent: parent}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.parent
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if req.Header.Get("Accept-Encoding") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", DefaultAcceptEncoding)
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method == http.MethodHead || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, nil
+	}
+
+	codings := parseContentEncoding(resp.Header.Get("Content-Encoding"))
+	for _, c := range codings {
+		if !isSupported(c) {
+			// leave responses with unknown codings as is
+			return resp, nil
+		}
+	}
+	if len(codings) == 0 {
+		return resp, nil
+	}
+
+	// codings are listed in the order in which they were applied
+	body := resp.Body
+	var r io.Reader = body
+	for i := len(codings) - 1; i >= 0; i-- {
+		r = &lazyReader{encoding: codings[i], src: r}
+	}
+	resp.Body = &decodedBody{Reader: r, body: body}
+
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+func parseContentEncoding(v string) []string {
+	var codings []string
+	for _, c := range strings.Split(v, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" || c == "identity" {
+			continue
+		}
+		if c == "x-gzip" {
+			c = Gzip
+		}
+		codings = append(codings, c)
+	}
+	return codings
+}
+
+func isSupported(encoding string) bool {
+	switch encoding {
+	case Brotli, Zstd, Gzip, Deflate:
+		return true
+	default:
+		return false
+	}
+}
+
+// lazyReader creates a decoder on first read, since some decoders read headers on creation.
+type lazyReader struct {
+	encoding string
+	src      io.Reader
+	r        io.Reader
+	closer   func()
+	err      error
+}
+
+func (r *lazyReader) Read(p []byte) (int, error) {
+	if r.r == nil && r.err == nil {
+		r.r, r.closer, r.err = newDecoder(r.encoding, r.src)
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.r.Read(p)
+}
+
+func (r *lazyReader) close() {
+	if r.closer != nil {
+		r.closer()
+	}
+	if src, ok := r.src.(*lazyReader); ok {
+		src.close()
+	}
+}
+
+func newDecoder(encoding string, r io.Reader) (io.Reader, func(), error) {
+	switch encoding {
+	case Brotli:
+		return brotli.NewReader(r), nil, nil
+	case Zstd:
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, nil, err
+		}
+		return d, d.Close, nil
+	case Gzip:
+		d, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return d, func() { _ = d.Close() }, nil
+	case Deflate:
+		// "deflate" should be zlib format (RFC 7230 Section 4.2.2), but some servers send raw deflate.
+		br := bufio.NewReader(r)
+		if h, err := br.Peek(2); err == nil && isZlibHeader(h) {
+			d, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, nil, err
+			}
+			return d, func() { _ = d.Close() }, nil
+		}
+		d := flate.NewReader(br)
+		return d, func() { _ = d.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("compress: unsupported content coding %q", encoding)
+	}
+}
+
+func isZlibHeader(h []byte) bool {
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
+
+// decodedBody closes decoders and the original body.
+type decodedBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	if r, ok := b.Reader.(*lazyReader); ok {
+		r.close()
+	}
+	return b.body.Close()
+}
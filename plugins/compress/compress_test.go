@@ -0,0 +1,161 @@
+package compress_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/izumin5210/hx"
+	"github.com/izumin5210/hx/plugins/compress"
+	"github.com/klauspost/compress/zstd"
+)
+
+const content = `{"message":"Hello, world! Hello, world! Hello, world!"}`
+
+func encode(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+		err error
+	)
+	switch encoding {
+	case compress.Brotli:
+		w = brotli.NewWriter(&buf)
+	case compress.Zstd:
+		w, err = zstd.NewWriter(&buf)
+	case compress.Gzip:
+		w = gzip.NewWriter(&buf)
+	case compress.Deflate:
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, err = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	if err != nil {
+		t.Fatalf("failed to create an encoder: %v", err)
+	}
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func decode(encoding string, r io.Reader) ([]byte, error) {
+	switch encoding {
+	case compress.Brotli:
+		return ioutil.ReadAll(brotli.NewReader(r))
+	case compress.Zstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer d.Close()
+		return ioutil.ReadAll(d)
+	case compress.Gzip:
+		d, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(d)
+	case compress.Deflate:
+		d, err := zlib.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(d)
+	}
+	return ioutil.ReadAll(r)
+}
+
+func TestDecompress(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings := strings.Split(r.URL.Query().Get("encoding"), ",")
+		data := []byte(content)
+		for _, enc := range encodings {
+			data = encode(t, enc, data)
+		}
+		if encodings[0] == "raw-deflate" {
+			encodings[0] = compress.Deflate
+		}
+		w.Header().Set("X-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", strings.Join(encodings, ", "))
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	for _, enc := range []string{"br", "zstd", "gzip", "deflate", "raw-deflate", "gzip,br"} {
+		t.Run(enc, func(t *testing.T) {
+			resp, err := hx.Do(context.Background(), http.MethodGet, ts.URL,
+				hx.Query("encoding", enc),
+				compress.Decompress(),
+				hx.WhenFailure(hx.AsError()),
+			)
+			if err != nil {
+				t.Fatalf("returned %v, want nil", err)
+			}
+			if got, want := string(resp.Bytes()), content; got != want {
+				t.Errorf("received %q, want %q", got, want)
+			}
+			if got, want := resp.Header.Get("X-Accept-Encoding"), compress.DefaultAcceptEncoding; got != want {
+				t.Errorf("sent Accept-Encoding %q, want %q", got, want)
+			}
+			if got := resp.Header.Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding is %q, want empty", got)
+			}
+			if got := resp.Header.Get("Content-Length"); got != "" {
+				t.Errorf("Content-Length is %q, want empty", got)
+			}
+		})
+	}
+}
+
+func TestCompressBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := decode(r.Header.Get("Content-Encoding"), r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("X-Content-Encoding", r.Header.Get("Content-Encoding"))
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	for _, enc := range []string{"br", "zstd", "gzip", "deflate"} {
+		t.Run(enc, func(t *testing.T) {
+			resp, err := hx.Do(context.Background(), http.MethodPost, ts.URL,
+				hx.Body(content),
+				compress.CompressBody(enc),
+				hx.WhenFailure(hx.AsError()),
+			)
+			if err != nil {
+				t.Fatalf("returned %v, want nil", err)
+			}
+			if got, want := string(resp.Bytes()), content; got != want {
+				t.Errorf("received %q, want %q", got, want)
+			}
+			if got, want := resp.Header.Get("X-Content-Encoding"), enc; got != want {
+				t.Errorf("sent Content-Encoding %q, want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		err := hx.Post(context.Background(), ts.URL,
+			hx.Body(content),
+			compress.CompressBody("lzma"),
+		)
+		if err == nil {
+			t.Error("returned nil, want an error")
+		}
+	})
+}
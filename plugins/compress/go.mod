@@ -0,0 +1,11 @@
+module github.com/izumin5210/hx/plugins/compress
+
+go 1.18
+
+replace github.com/izumin5210/hx => ../../
+
+require (
+	github.com/andybalholm/brotli v1.0.5
+	github.com/izumin5210/hx v0.3.0
+	github.com/klauspost/compress v1.15.15
+)
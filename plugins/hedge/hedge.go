@@ -0,0 +1,246 @@
+// A plugin for hedging requests to reduce tail latencies.
+package hedge
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/izumin5210/hx"
+)
+
+var now = time.Now
+
+// Stats is statistics of a hedged request.
+type Stats struct {
+	// Hedges is the number of hedged requests sent in addition to the original request.
+	Hedges int
+	// Winner is the index of the request whose response is returned. 0 means the original request.
+	Winner int
+}
+
+type statsKey struct{}
+
+// WithStats returns a context that collects statistics of a request into s.
+// If the request is redirected, s holds the statistics of the last one.
+//  var stats hedge.Stats
+//  err := hx.Get(hedge.WithStats(ctx, &stats), url, hedge.Use(h))
+//  log.Printf("hedges: %d, winner: %d", stats.Hedges, stats.Winner)
+func WithStats(ctx context.Context, s *Stats) context.Context {
+	return context.WithValue(ctx, statsKey{}, s)
+}
+
+// Hedger sends hedged requests if the original request does not respond within a delay.
+// It should be shared between requests to derive delays from observed latencies.
+type Hedger struct {
+	cfg       *config
+	latencies *latencies
+}
+
+var _ hx.Interceptor = (*Hedger)(nil)
+
+// New creates a Hedger.
+//  h := hedge.New(
+//  	hedge.Delay(100*time.Millisecond),
+//  	hedge.Percentile(0.95, 1000),
+//  	hedge.MaxHedges(2),
+//  )
+//
+//  err := hx.Get(ctx, "https://api.example.com/contents/1",
+//  	hedge.Use(h),
+//  	hx.WhenSuccess(hx.AsJSON(&cont)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func New(opts ...Option) *Hedger {
+	cfg := newConfig(opts)
+	h := &Hedger{cfg: cfg}
+	if cfg.percentile > 0 && cfg.window > 0 {
+		h.latencies = newLatencies(cfg.window)
+	}
+	return h
+}
+
+// Use creates an option that hedges requests with a given hedger.
+func Use(h *Hedger) hx.Option {
+	return hx.Intercept(h)
+}
+
+// DoRequest implements hx.Interceptor.
+// Only requests with idempotent methods and replayable bodies are hedged.
+func (h *Hedger) DoRequest(cli *http.Client, req *http.Request, next hx.RequestFunc) (*http.Response, error) {
+	if !isIdempotent(req.Method) || !isReplayable(req) {
+		return next(cli, req)
+	}
+
+	newCli := *cli
+	newCli.Transport = &transport{parent: cli.Transport, h: h}
+	return next(&newCli, req)
+}
+
+func (h *Hedger) Wrap(f hx.RequestFunc) hx.RequestFunc {
+	return func(c *http.Client, r *http.Request) (*http.Response, error) { return h.DoRequest(c, r, f) }
+}
+
+func (h *Hedger) delay() time.Duration {
+	if h.latencies != nil {
+		if d, ok := h.latencies.percentile(h.cfg.percentile); ok {
+			return d
+		}
+	}
+	return h.cfg.delay
+}
+
+func (h *Hedger) observe(startedAt time.Time) {
+	if h.latencies != nil {
+		h.latencies.add(now().Sub(startedAt))
+	}
+}
+
+// transport sends hedged requests.
+type transport struct {
+	parent http.RoundTripper
+	h      *Hedger
+}
+
+type result struct {
+	resp *http.Response
+	err  error
+	i    int
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.parent
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	startedAt := now()
+
+	delay := t.h.delay()
+	if delay <= 0 || t.h.cfg.maxHedges <= 0 {
+		resp, err := next.RoundTrip(req)
+		if err == nil {
+			t.h.observe(startedAt)
+		}
+		return resp, err
+	}
+
+	ctx := req.Context()
+	results := make(chan result, t.h.cfg.maxHedges+1)
+	var cancels []context.CancelFunc
+
+	send := func(i int) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+
+		var r *http.Request
+		if i == 0 {
+			r = req.WithContext(cctx)
+		} else {
+			r = req.Clone(cctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					results <- result{err: err, i: i}
+					return
+				}
+				r.Body = body
+			}
+		}
+
+		go func() {
+			resp, err := next.RoundTrip(r)
+			results <- result{resp: resp, err: err, i: i}
+		}()
+	}
+
+	send(0)
+	sent, pending := 1, 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var res result
+	for {
+		select {
+		case res = <-results:
+			pending--
+		case <-timer.C:
+			send(sent)
+			sent++
+			pending++
+			if sent <= t.h.cfg.maxHedges {
+				timer.Reset(delay)
+			}
+			continue
+		}
+		// wait for other requests if the request failed
+		if res.err == nil || pending == 0 {
+			break
+		}
+	}
+
+	for i, cancel := range cancels {
+		if i != res.i {
+			cancel()
+		}
+	}
+	if pending > 0 {
+		go discard(results, pending)
+	}
+
+	if s, ok := ctx.Value(statsKey{}).(*Stats); ok {
+		s.Hedges = sent - 1
+		s.Winner = res.i
+	}
+
+	if res.err != nil {
+		cancels[res.i]()
+		return nil, res.err
+	}
+
+	// record the latency of the original request.
+	// If a hedged request wins, the elapsed time is recorded as a lower bound of it since the original one is cancelled.
+	t.h.observe(startedAt)
+
+	// the context of the winner should be alive until the body is closed.
+	res.resp.Body = &cancelBody{ReadCloser: res.resp.Body, cancel: cancels[res.i]}
+	return res.resp, nil
+}
+
+// discard closes responses of losers.
+func discard(results <-chan result, n int) {
+	for i := 0; i < n; i++ {
+		res := <-results
+		if res.resp != nil {
+			_ = res.resp.Body.Close()
+		}
+	}
+}
+
+// cancelBody cancels the request context when the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// isIdempotent reports whether a method is idempotent (RFC 7231 Section 4.2.2).
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func isReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
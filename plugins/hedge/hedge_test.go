@@ -0,0 +1,203 @@
+package hedge_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/hx"
+	"github.com/izumin5210/hx/plugins/hedge"
+)
+
+func TestHedger(t *testing.T) {
+	var (
+		cnt       int32
+		mu        sync.Mutex
+		bodies    []string
+		cancelled = make(chan struct{}, 10)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&cnt, 1)
+		data, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(data))
+		mu.Unlock()
+
+		if r.URL.Path == "/slow" && n == 1 {
+			select {
+			case <-r.Context().Done():
+				cancelled <- struct{}{}
+				return
+			case <-time.After(300 * time.Millisecond):
+			}
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	reset := func() {
+		atomic.StoreInt32(&cnt, 0)
+		mu.Lock()
+		bodies = nil
+		mu.Unlock()
+	}
+
+	h := hedge.New(hedge.Delay(20 * time.Millisecond))
+
+	cases := []struct {
+		test   string
+		meth   string
+		path   string
+		opts   []hx.Option
+		stats  hedge.Stats
+		sent   int32
+		bodies []string
+	}{
+		{
+			test:  "hedged",
+			meth:  http.MethodGet,
+			path:  "/slow",
+			stats: hedge.Stats{Hedges: 1, Winner: 1},
+			sent:  2,
+		},
+		{
+			test:  "fast",
+			meth:  http.MethodGet,
+			path:  "/fast",
+			stats: hedge.Stats{Hedges: 0, Winner: 0},
+			sent:  1,
+		},
+		{
+			test:   "replayable body",
+			meth:   http.MethodPut,
+			path:   "/slow",
+			opts:   []hx.Option{hx.Body("hello")},
+			stats:  hedge.Stats{Hedges: 1, Winner: 1},
+			sent:   2,
+			bodies: []string{"hello", "hello"},
+		},
+		{
+			test:  "non-idempotent",
+			meth:  http.MethodPost,
+			path:  "/slow",
+			opts:  []hx.Option{hx.Body("hello")},
+			stats: hedge.Stats{Hedges: -1, Winner: -1},
+			sent:  1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			reset()
+
+			stats := hedge.Stats{Hedges: -1, Winner: -1}
+			ctx := hedge.WithStats(context.Background(), &stats)
+
+			var buf []byte
+			opts := append(tc.opts,
+				hedge.Use(h),
+				hx.WhenSuccess(func(r *http.Response, err error) (*http.Response, error) {
+					defer r.Body.Close()
+					buf, err = ioutil.ReadAll(r.Body)
+					return r, err
+				}),
+				hx.WhenFailure(hx.AsError()),
+			)
+			err := hx.Request(ctx, tc.meth, ts.URL+tc.path, opts...)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if got, want := string(buf), "ok"; got != want {
+				t.Errorf("received %q, want %q", got, want)
+			}
+			if got, want := stats, tc.stats; got != want {
+				t.Errorf("stats is %+v, want %+v", got, want)
+			}
+			if got, want := atomic.LoadInt32(&cnt), tc.sent; got != want {
+				t.Errorf("sent %d requests, want %d", got, want)
+			}
+			if tc.bodies != nil {
+				mu.Lock()
+				got := bodies
+				mu.Unlock()
+				if len(got) != len(tc.bodies) || got[0] != tc.bodies[0] || got[1] != tc.bodies[1] {
+					t.Errorf("received bodies %q, want %q", got, tc.bodies)
+				}
+			}
+			if tc.stats.Hedges > 0 {
+				select {
+				case <-cancelled:
+				case <-time.After(500 * time.Millisecond):
+					t.Error("the original request is not cancelled")
+				}
+			}
+		})
+	}
+}
+
+func TestHedger_Percentile(t *testing.T) {
+	var slow int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.CompareAndSwapInt32(&slow, 1, 0) {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		test string
+		opts []hedge.Option
+	}{
+		{
+			test: "with delay",
+			opts: []hedge.Option{hedge.Delay(time.Hour), hedge.Percentile(0.9, 10)},
+		},
+		{
+			test: "without delay",
+			opts: []hedge.Option{hedge.Percentile(0.9, 10)},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			h := hedge.New(tc.opts...)
+
+			get := func(stats *hedge.Stats) error {
+				return hx.Get(hedge.WithStats(context.Background(), stats), ts.URL, hedge.Use(h), hx.WhenFailure(hx.AsError()))
+			}
+
+			for i := 0; i < 10; i++ {
+				var stats hedge.Stats
+				if err := get(&stats); err != nil {
+					t.Errorf("returned %v, want nil", err)
+				}
+				if got, want := stats, (hedge.Stats{}); got != want {
+					t.Errorf("stats is %+v, want %+v", got, want)
+				}
+			}
+
+			atomic.StoreInt32(&slow, 1)
+			startedAt := time.Now()
+			var stats hedge.Stats
+			if err := get(&stats); err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if got, want := stats, (hedge.Stats{Hedges: 1, Winner: 1}); got != want {
+				t.Errorf("stats is %+v, want %+v", got, want)
+			}
+			if got, max := time.Since(startedAt), 500*time.Millisecond; got > max {
+				t.Errorf("took %v, want <= %v", got, max)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package hedge
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// minSamples is the number of latencies required to derive delays from percentiles.
+const minSamples = 10
+
+// latencies is a ring buffer of observed latencies.
+type latencies struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func newLatencies(size int) *latencies {
+	return &latencies{samples: make([]time.Duration, 0, size)}
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.samples) < cap(l.samples) {
+		l.samples = append(l.samples, d)
+		return
+	}
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % len(l.samples)
+}
+
+// percentile returns p-th percentile of latencies. It returns false if there are not enough samples.
+func (l *latencies) percentile(p float64) (time.Duration, bool) {
+	l.mu.Lock()
+	samples := make([]time.Duration, len(l.samples))
+	copy(samples, l.samples)
+	l.mu.Unlock()
+
+	if len(samples) < minSamples {
+		return 0, false
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	i := int(p * float64(len(samples)))
+	if i >= len(samples) {
+		i = len(samples) - 1
+	}
+	return samples[i], true
+}
@@ -0,0 +1,41 @@
+package hedge
+
+import "time"
+
+type Option func(*config)
+
+type config struct {
+	delay      time.Duration
+	percentile float64
+	window     int
+	maxHedges  int
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{maxHedges: 1}
+	for _, f := range opts {
+		f(c)
+	}
+	return c
+}
+
+// Delay is the duration to wait for a response before sending a hedged request.
+// It is used until enough latencies are observed if Percentile is also specified.
+func Delay(d time.Duration) Option {
+	return func(c *config) { c.delay = d }
+}
+
+// Percentile derives the delay from a given percentile (e.g. 0.95) of the latest window latencies.
+// Latencies of the original requests are observed even if they are not hedged.
+// If Delay is not specified, requests are not hedged until enough latencies are observed.
+func Percentile(p float64, window int) Option {
+	return func(c *config) {
+		c.percentile = p
+		c.window = window
+	}
+}
+
+// MaxHedges limits the number of hedged requests sent in addition to the original request. It is 1 in default.
+func MaxHedges(n int) Option {
+	return func(c *config) { c.maxHedges = n }
+}
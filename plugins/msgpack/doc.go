@@ -0,0 +1,2 @@
+// A plugin for marshaling and unmarshaling MessagePack.
+package msgpack
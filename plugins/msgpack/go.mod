@@ -0,0 +1,12 @@
+module github.com/izumin5210/hx/plugins/msgpack
+
+go 1.18
+
+replace github.com/izumin5210/hx => ../../
+
+require (
+	github.com/izumin5210/hx v0.3.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
@@ -0,0 +1,109 @@
+package msgpack
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/izumin5210/hx"
+	msgpackv5 "github.com/vmihailenco/msgpack/v5"
+)
+
+// ContentType is a media type of MessagePack set to requests.
+const ContentType = "application/msgpack"
+
+var (
+	DefaultConfig = &Config{}
+
+	contentTypeMsgPack = hx.Header("Content-Type", ContentType)
+)
+
+func init() {
+	decode := func(r io.Reader, v interface{}) error { return DefaultConfig.decode(r, v) }
+	hx.RegisterDecoder(ContentType, decode)
+	hx.RegisterDecoder("application/x-msgpack", decode)
+	hx.RegisterDecoder("application/vnd.msgpack", decode)
+}
+
+// MsgPack sets data to request body as MessagePack.
+// This will marshal a given data with msgpack.Marshal in default.
+func MsgPack(v interface{}) hx.Option {
+	return DefaultConfig.MsgPack(v)
+}
+
+// AsMsgPack is hx.ResponseHandler for unmarshaling response bodies as MessagePack.
+// This will unmarshal a received data with msgpack.Decoder in default.
+func AsMsgPack(dst interface{}) hx.ResponseHandler {
+	return DefaultConfig.AsMsgPack(dst)
+}
+
+type Config struct {
+	EncodeFunc func(interface{}) (io.Reader, error)
+	DecodeFunc func(io.Reader, interface{}) error
+}
+
+func (c *Config) MsgPack(v interface{}) hx.Option {
+	return hx.OptionFunc(func(hc *hx.Config) error {
+		r, err := c.encode(v)
+		if err != nil {
+			return err
+		}
+		hc.Body = r
+		hc.GetBody = nil
+		return contentTypeMsgPack.ApplyOption(hc)
+	})
+}
+
+func (c *Config) AsMsgPack(dst interface{}) hx.ResponseHandler {
+	return func(r *http.Response, err error) (*http.Response, error) {
+		if r == nil || err != nil {
+			return r, err
+		}
+		if !hasBody(r) {
+			return r, nil
+		}
+
+		defer r.Body.Close()
+		err = c.decode(r.Body, dst)
+		if err != nil {
+			return nil, &hx.ResponseError{Response: r, Err: err}
+		}
+		return r, nil
+	}
+}
+
+func (c *Config) encode(v interface{}) (io.Reader, error) {
+	if f := c.EncodeFunc; f != nil {
+		return f(v)
+	}
+
+	data, err := msgpackv5.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (c *Config) decode(r io.Reader, v interface{}) error {
+	if f := c.DecodeFunc; f != nil {
+		return f(r, v)
+	}
+
+	return msgpackv5.NewDecoder(r).Decode(v)
+}
+
+// hasBody reports whether a given response can have a body, in the same way as response handlers of hx.
+// Responses to HEAD requests, and responses with 1xx, 204 or 304 status never have a body.
+func hasBody(r *http.Response) bool {
+	if r.Body == nil {
+		return false
+	}
+	if r.Request != nil && r.Request.Method == http.MethodHead {
+		return false
+	}
+	switch {
+	case r.StatusCode/100 == 1, r.StatusCode == http.StatusNoContent, r.StatusCode == http.StatusNotModified:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/izumin5210/hx"
+	"github.com/izumin5210/hx/plugins/msgpack"
+	msgpackv5 "github.com/vmihailenco/msgpack/v5"
+)
+
+type Message struct {
+	Name     string     `msgpack:"name"`
+	Score    int64      `msgpack:"score"`
+	Tags     []string   `msgpack:"tags"`
+	Children []*Message `msgpack:"children"`
+}
+
+func TestMsgPack(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/echo":
+			if r.Header.Get("Content-Type") != "application/msgpack" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			var msg Message
+			err := msgpackv5.NewDecoder(r.Body).Decode(&msg)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			data, err := msgpackv5.Marshal(&msg)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/msgpack")
+			w.Write(data)
+
+		case r.Method == http.MethodGet && r.URL.Path == "/broken":
+			w.Header().Set("Content-Type", "application/msgpack")
+			w.Write([]byte{0xc1})
+
+		case r.Method == http.MethodGet && r.URL.Path == "/empty":
+			w.Header().Set("Content-Type", "application/msgpack")
+
+		case r.Method == http.MethodGet && r.URL.Path == "/no-content":
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	want := &Message{
+		Name:  "It, Works!",
+		Score: 120,
+		Tags:  []string{"foo", "bar"},
+		Children: []*Message{
+			{Name: "foo"},
+			{Name: "bar", Score: 1},
+		},
+	}
+
+	t.Run("simple", func(t *testing.T) {
+		var got Message
+		err := hx.Post(context.Background(), ts.URL+"/echo",
+			msgpack.MsgPack(want),
+			hx.WhenSuccess(msgpack.AsMsgPack(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		assertMessage(t, want, &got)
+	})
+
+	t.Run("custom encoder", func(t *testing.T) {
+		var got, overwrited Message
+		overwrited = *want
+		overwrited.Name = "It, Works!!!!!!!!!!!!!!!!!!!!!!"
+
+		cfg := &msgpack.Config{
+			EncodeFunc: func(_ interface{}) (io.Reader, error) {
+				data, err := msgpackv5.Marshal(&overwrited)
+				if err != nil {
+					return nil, err
+				}
+				return bytes.NewReader(data), nil
+			},
+		}
+		err := hx.Post(context.Background(), ts.URL+"/echo",
+			cfg.MsgPack(want),
+			hx.WhenSuccess(msgpack.AsMsgPack(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		assertMessage(t, &overwrited, &got)
+	})
+
+	t.Run("custom decoder", func(t *testing.T) {
+		var got, overwrited Message
+		overwrited = *want
+		overwrited.Name = "It, Works!!!!!!!!!!!!!!!!!!!!!!"
+
+		cfg := &msgpack.Config{
+			DecodeFunc: func(r io.Reader, v interface{}) error {
+				(*v.(*Message)) = *want
+				return nil
+			},
+		}
+		err := hx.Post(context.Background(), ts.URL+"/echo",
+			msgpack.MsgPack(&overwrited),
+			hx.WhenSuccess(cfg.AsMsgPack(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		assertMessage(t, want, &got)
+	})
+
+	t.Run("auto", func(t *testing.T) {
+		var got Message
+		err := hx.Post(context.Background(), ts.URL+"/echo",
+			msgpack.MsgPack(want),
+			hx.WhenSuccess(hx.AsAuto(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		assertMessage(t, want, &got)
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		var got Message
+		err := hx.Get(context.Background(), ts.URL+"/empty",
+			hx.WhenSuccess(msgpack.AsMsgPack(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("returned %v, want *hx.ResponseError", err)
+		}
+	})
+
+	t.Run("no content", func(t *testing.T) {
+		var got Message
+		err := hx.Get(context.Background(), ts.URL+"/no-content",
+			hx.WhenSuccess(msgpack.AsMsgPack(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+	})
+
+	t.Run("failed to decode response", func(t *testing.T) {
+		var got Message
+		err := hx.Get(context.Background(), ts.URL+"/broken",
+			hx.WhenSuccess(msgpack.AsMsgPack(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("returned %v, want *hx.ResponseError", err)
+		}
+	})
+}
+
+func assertMessage(t *testing.T, want, got *Message) {
+	t.Helper()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("received %+v, want %+v", got, want)
+	}
+}
@@ -1,6 +1,8 @@
 module github.com/izumin5210/hx/plugins/pb
 
-go 1.13
+go 1.18
+
+replace github.com/izumin5210/hx => ../../
 
 require (
 	github.com/golang/protobuf v1.3.2
@@ -2,6 +2,7 @@ package pb
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -11,6 +12,18 @@ import (
 
 var DefaultProtoConfig = &ProtoConfig{}
 
+func init() {
+	decode := func(r io.Reader, v interface{}) error {
+		pb, ok := v.(proto.Message)
+		if !ok {
+			return fmt.Errorf("pb: %T is not proto.Message", v)
+		}
+		return DefaultProtoConfig.decode(r, pb)
+	}
+	hx.RegisterDecoder("application/x-protobuf", decode)
+	hx.RegisterDecoder("application/protobuf", decode)
+}
+
 // Proto sets proto.Message to request body as protocol buffers.
 // This will marshal a given data with proto.Marshal in default.
 func Proto(pb proto.Message) hx.Option {
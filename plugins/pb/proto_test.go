@@ -44,6 +44,7 @@ func TestProto(t *testing.T) {
 				return
 			}
 
+			w.Header().Set("Content-Type", "application/x-protobuf")
 			w.Write(data)
 
 		default:
@@ -121,6 +122,20 @@ func TestProto(t *testing.T) {
 		}
 		assertProtoMessage(t, want, &got)
 	})
+
+	t.Run("auto", func(t *testing.T) {
+		var got proto3_proto.Message
+		err := hx.Post(context.Background(), ts.URL+"/echo",
+			pb.Proto(want),
+			hx.AcceptAuto(),
+			hx.WhenSuccess(hx.AsAuto(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		assertProtoMessage(t, want, &got)
+	})
 }
 
 func assertProtoMessage(t *testing.T, want proto.Message, got proto.Message) {
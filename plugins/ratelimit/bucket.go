@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// bucket holds limiting states for a key.
+type bucket struct {
+	cfg *config
+	sem chan struct{}
+
+	mu           sync.Mutex
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+	// refs is the number of requests using the bucket, from Limiter.bucket to the end of the request.
+	refs int
+}
+
+func newBucket(cfg *config) *bucket {
+	b := &bucket{cfg: cfg, tokens: float64(cfg.burst), last: now()}
+	if cfg.maxConcurrency > 0 {
+		b.sem = make(chan struct{}, cfg.maxConcurrency)
+	}
+	return b
+}
+
+// wait blocks until a request is allowed. It returns a function that should be called when the request is finished.
+// The reference taken by Limiter.bucket is released when the request is finished, or wait fails.
+func (b *bucket) wait(ctx context.Context) (func(), error) {
+	d := b.reserve(now())
+	if d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			b.cancel()
+			b.release()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	var once sync.Once
+	if b.sem == nil {
+		return func() { once.Do(b.release) }, nil
+	}
+	select {
+	case <-ctx.Done():
+		b.cancel()
+		b.release()
+		return nil, ctx.Err()
+	case b.sem <- struct{}{}:
+		return func() {
+			once.Do(func() {
+				<-b.sem
+				b.release()
+			})
+		}, nil
+	}
+}
+
+func (b *bucket) acquire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refs++
+}
+
+func (b *bucket) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refs--
+}
+
+// idle reports whether the bucket can be dropped without changing limits,
+// i.e. no requests use it, its tokens are full and it is not blocked.
+func (b *bucket) idle(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.refs > 0 || now.Before(b.blockedUntil) {
+		return false
+	}
+	if r := b.cfg.rate; r > 0 {
+		return b.tokens+now.Sub(b.last).Seconds()*r >= float64(b.cfg.burst)
+	}
+	return true
+}
+
+// reserve takes a token and returns the duration to wait for it.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var d time.Duration
+	if r := b.cfg.rate; r > 0 {
+		b.tokens += now.Sub(b.last).Seconds() * r
+		if max := float64(b.cfg.burst); b.tokens > max {
+			b.tokens = max
+		}
+		b.last = now
+		b.tokens--
+		if b.tokens < 0 {
+			d = time.Duration(-b.tokens / r * float64(time.Second))
+		}
+	}
+	if until := b.blockedUntil.Sub(now); until > d {
+		d = until
+	}
+	return d
+}
+
+// cancel returns a token taken by reserve.
+func (b *bucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.rate > 0 {
+		b.tokens++
+	}
+}
+
+// update blocks requests until the quota is reset if a given response tells that no requests remain.
+func (b *bucket) update(h http.Header, now time.Time) {
+	remaining, ok := headerInt(h, "RateLimit-Remaining", "X-RateLimit-Remaining")
+	if !ok || remaining > 0 {
+		return
+	}
+	reset, ok := headerInt(h, "RateLimit-Reset", "X-RateLimit-Reset")
+	if !ok || reset < 0 {
+		return
+	}
+
+	var until time.Time
+	if reset > unixTimeThreshold {
+		until = time.Unix(reset, 0)
+	} else {
+		until = now.Add(time.Duration(reset) * time.Second)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+// unixTimeThreshold distinguishes Unix times from delta seconds in reset headers. 1e9 is 2001-09-09.
+const unixTimeThreshold = 1e9
+
+func headerInt(h http.Header, keys ...string) (int64, bool) {
+	for _, k := range keys {
+		if v := h.Get(k); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,15 @@
+package ratelimit
+
+import "time"
+
+func SetNow(f func() time.Time) func() {
+	tmp := now
+	now = f
+	return func() { now = tmp }
+}
+
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import "net/http"
+
+type Option func(*config)
+
+type config struct {
+	rate           float64
+	burst          int
+	maxConcurrency int
+	keyFunc        func(*http.Request) string
+	adaptive       bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		keyFunc: func(r *http.Request) string { return r.URL.Host },
+	}
+	for _, f := range opts {
+		f(c)
+	}
+	if c.burst <= 0 {
+		c.burst = 1
+	}
+	return c
+}
+
+// Rate limits requests to r requests per second with bursts of up to burst requests.
+func Rate(r float64, burst int) Option {
+	return func(c *config) {
+		c.rate = r
+		c.burst = burst
+	}
+}
+
+// MaxConcurrency limits the number of in-flight requests.
+// A request is in flight until its response body is closed.
+func MaxConcurrency(n int) Option {
+	return func(c *config) { c.maxConcurrency = n }
+}
+
+// PerHost applies limits for each host. It is the default.
+func PerHost() Option {
+	return KeyFunc(func(r *http.Request) string { return r.URL.Host })
+}
+
+// Global applies limits to all requests.
+func Global() Option {
+	return KeyFunc(func(*http.Request) string { return "" })
+}
+
+// KeyFunc applies limits for each key returned by a given function, e.g. per API token.
+func KeyFunc(f func(*http.Request) string) Option {
+	return func(c *config) { c.keyFunc = f }
+}
+
+// Adaptive waits until the quota is reset when the server tells that no requests remain,
+// by RateLimit-Remaining / RateLimit-Reset (draft-ietf-httpapi-ratelimit-headers)
+// or X-RateLimit-Remaining / X-RateLimit-Reset headers.
+// The reset value is treated as a Unix time if it is large enough, and as delta seconds otherwise.
+func Adaptive() Option {
+	return func(c *config) { c.adaptive = true }
+}
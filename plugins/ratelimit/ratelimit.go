@@ -0,0 +1,135 @@
+// A plugin for client-side rate limiting.
+package ratelimit
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/hx"
+)
+
+var now = time.Now
+
+// Limiter limits requests for each key. It should be shared between requests.
+type Limiter struct {
+	cfg *config
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// sweepInterval is the interval to drop idle buckets, so that per-key limits (e.g. per API token) do not leak buckets.
+const sweepInterval = time.Minute
+
+// New creates a Limiter.
+//  // 10 requests per second, 3 concurrent requests for each API token
+//  l := ratelimit.New(
+//  	ratelimit.Rate(10, 1),
+//  	ratelimit.MaxConcurrency(3),
+//  	ratelimit.KeyFunc(func(r *http.Request) string { return r.Header.Get("Authorization") }),
+//  	ratelimit.Adaptive(),
+//  )
+//
+//  err := hx.Get(ctx, "https://api.example.com/contents/1",
+//  	ratelimit.Use(l),
+//  	hx.WhenSuccess(hx.AsJSON(&cont)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func New(opts ...Option) *Limiter {
+	return &Limiter{cfg: newConfig(opts), buckets: map[string]*bucket{}, lastSweep: now()}
+}
+
+// Use creates an option that sends requests through a given limiter.
+func Use(l *Limiter) hx.Option {
+	return hx.TransportFrom(l.Wrap)
+}
+
+// Wrap returns a http.RoundTripper that waits for limits before sending requests.
+func (l *Limiter) Wrap(parent http.RoundTripper) http.RoundTripper {
+	return &Transport{parent: parent, l: l}
+}
+
+// bucket returns a bucket for a key with a reference, which is released by bucket.wait.
+func (l *Limiter) bucket(key string) *bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if t := now(); t.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(t)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(l.cfg)
+		l.buckets[key] = b
+	}
+	b.acquire()
+	return b
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for k, b := range l.buckets {
+		if b.idle(now) {
+			delete(l.buckets, k)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Transport is a http.RoundTripper that waits for limits before sending requests.
+type Transport struct {
+	parent http.RoundTripper
+	l      *Limiter
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.l.bucket(t.l.cfg.keyFunc(req))
+
+	done, err := b.wait(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	next := t.parent
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		done()
+		return nil, err
+	}
+
+	if t.l.cfg.adaptive {
+		b.update(resp.Header, now())
+	}
+
+	if resp.Body == nil || resp.Body == http.NoBody {
+		done()
+	} else {
+		resp.Body = &doneBody{ReadCloser: resp.Body, done: done}
+	}
+
+	return resp, nil
+}
+
+// doneBody calls done when the body is closed.
+type doneBody struct {
+	io.ReadCloser
+	done func()
+}
+
+func (b *doneBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
@@ -0,0 +1,226 @@
+package ratelimit_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/hx"
+	"github.com/izumin5210/hx/plugins/ratelimit"
+)
+
+func TestLimiter_Rate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	l := ratelimit.New(ratelimit.Rate(50, 1))
+
+	startedAt := time.Now()
+	for i := 0; i < 5; i++ {
+		err := hx.Get(context.Background(), ts.URL, ratelimit.Use(l))
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+	}
+	if got, want := time.Since(startedAt), 80*time.Millisecond; got < want {
+		t.Errorf("took %v, want >= %v", got, want)
+	}
+
+	t.Run("context", func(t *testing.T) {
+		l := ratelimit.New(ratelimit.Rate(1, 1))
+		_ = hx.Get(context.Background(), ts.URL, ratelimit.Use(l))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := hx.Get(ctx, ts.URL, ratelimit.Use(l))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("returned %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("per key", func(t *testing.T) {
+		l := ratelimit.New(
+			ratelimit.Rate(1, 1),
+			ratelimit.KeyFunc(func(r *http.Request) string { return r.Header.Get("Authorization") }),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		for _, token := range []string{"foo", "bar", "baz"} {
+			err := hx.Get(ctx, ts.URL, hx.Bearer(token), ratelimit.Use(l))
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+		}
+	})
+}
+
+func TestLimiter_MaxConcurrency(t *testing.T) {
+	var cur, max int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&cur, 1)
+		defer atomic.AddInt32(&cur, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	l := ratelimit.New(ratelimit.MaxConcurrency(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := hx.Get(context.Background(), ts.URL, ratelimit.Use(l), hx.WhenSuccess(hx.AsBytesBuffer(new(bytes.Buffer))))
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := atomic.LoadInt32(&max), int32(2); got != want {
+		t.Errorf("max concurrency is %d, want %d", got, want)
+	}
+}
+
+func TestLimiter_RateAndConcurrency(t *testing.T) {
+	l := ratelimit.New(ratelimit.Rate(1, 2), ratelimit.MaxConcurrency(1))
+	rt := l.Wrap(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("")), Request: r}, nil
+	}))
+	get := func(ctx context.Context) (*http.Response, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.example.com/", nil)
+		return rt.RoundTrip(req)
+	}
+
+	resp, err := get(context.Background())
+	if err != nil {
+		t.Fatalf("returned %v, want nil", err)
+	}
+
+	// a token is taken, but the request is canceled while waiting for the in-flight request
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	resp.Body.Close()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	resp, err = get(ctx)
+	if err != nil {
+		t.Fatalf("returned %v, want nil", err)
+	}
+	resp.Body.Close()
+}
+
+func TestLimiter_IdleBuckets(t *testing.T) {
+	current := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer ratelimit.SetNow(func() time.Time { return current })()
+
+	l := ratelimit.New(
+		ratelimit.Rate(1, 1),
+		ratelimit.KeyFunc(func(r *http.Request) string { return r.Header.Get("Authorization") }),
+	)
+	rt := l.Wrap(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	}))
+	get := func(token string) {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+		req.Header.Set("Authorization", token)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+	}
+
+	for _, token := range []string{"foo", "bar", "baz"} {
+		get(token)
+	}
+	if got, want := l.Len(), 3; got != want {
+		t.Errorf("has %d buckets, want %d", got, want)
+	}
+
+	current = current.Add(time.Minute)
+	get("qux")
+	if got, want := l.Len(), 1; got != want {
+		t.Errorf("has %d buckets, want %d", got, want)
+	}
+}
+
+func TestLimiter_Adaptive(t *testing.T) {
+	reset := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var cnt int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&cnt, 1) > 1 {
+			return
+		}
+		switch r.URL.Path {
+		case "/x":
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		case "/draft":
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", "60")
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("X-RateLimit-*", func(t *testing.T) {
+		atomic.StoreInt32(&cnt, 0)
+		defer ratelimit.SetNow(func() time.Time { return reset.Add(-100 * time.Millisecond) })()
+
+		l := ratelimit.New(ratelimit.Adaptive())
+
+		startedAt := time.Now()
+		for i := 0; i < 2; i++ {
+			err := hx.Get(context.Background(), ts.URL+"/x", ratelimit.Use(l))
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+		}
+		if got, want := time.Since(startedAt), 100*time.Millisecond; got < want {
+			t.Errorf("took %v, want >= %v", got, want)
+		}
+	})
+
+	t.Run("RateLimit-*", func(t *testing.T) {
+		atomic.StoreInt32(&cnt, 0)
+		l := ratelimit.New(ratelimit.Adaptive())
+
+		err := hx.Get(context.Background(), ts.URL+"/draft", ratelimit.Use(l))
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err = hx.Get(ctx, ts.URL+"/draft", ratelimit.Use(l))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("returned %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,187 @@
+package retry
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// DefaultMaxBufferSize is the default max size of request bodies buffered in memory for retries.
+const DefaultMaxBufferSize = 1 << 20
+
+// ErrBodyTooLarge is returned when a request should be retried but its body cannot be sent again,
+// since it is larger than the max buffer size and it is neither rewindable nor has GetBody.
+var ErrBodyTooLarge = errors.New("retry: request body is too large to retry")
+
+// rewinder returns a new request body for the next attempt.
+type rewinder func() (io.ReadCloser, error)
+
+// newRewinder prepares replaying request bodies.
+// It prefers http.Request.GetBody and io.Seeker, and buffers bodies in memory up to maxBufSize bytes otherwise.
+// It returns the body for the first attempt, and cleanup func that should be called after all attempts.
+func newRewinder(req *http.Request, maxBufSize int64) (io.ReadCloser, rewinder, func(), error) {
+	body := req.Body
+	nop := func() {}
+
+	if body == nil || body == http.NoBody {
+		return body, func() (io.ReadCloser, error) { return body, nil }, nop, nil
+	}
+
+	if req.GetBody != nil {
+		return body, req.GetBody, nop, nil
+	}
+
+	if s, ok := body.(io.ReadSeeker); ok {
+		if sb, err := newSeekableBody(body, s); err == nil {
+			rewind := func() (io.ReadCloser, error) { return sb.next(), nil }
+			return sb.next(), rewind, sb.finish, nil
+		}
+	}
+
+	tooLarge := func() (io.ReadCloser, error) { return nil, ErrBodyTooLarge }
+
+	if req.ContentLength > maxBufSize {
+		return body, tooLarge, nop, nil
+	}
+
+	buf, err := ioutil.ReadAll(io.LimitReader(body, maxBufSize+1))
+	if err != nil {
+		_ = body.Close()
+		return nil, nil, nop, err
+	}
+	if int64(len(buf)) > maxBufSize {
+		// send buffered bytes and the rest of the body only once.
+		return &readCloser{Reader: io.MultiReader(bytes.NewReader(buf), body), Closer: body}, tooLarge, nop, nil
+	}
+
+	err = body.Close()
+	if err != nil {
+		return nil, nil, nop, err
+	}
+	rewind := func() (io.ReadCloser, error) { return ioutil.NopCloser(bytes.NewReader(buf)), nil }
+	first, _ := rewind()
+	return first, rewind, nop, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// seekableBody replays a body implementing io.Seeker.
+// http.Transport may keep reading a request body even after RoundTrip returns, so each attempt gets its own reader,
+// and the original body is closed after the readers of all attempts are closed.
+type seekableBody struct {
+	body io.Closer
+	s    io.ReadSeeker
+	ra   io.ReaderAt // nil if the body does not implement io.ReaderAt
+	pos  int64
+	size int64
+
+	mu         sync.Mutex
+	open       int
+	done       bool
+	prevClosed chan struct{}
+}
+
+func newSeekableBody(body io.Closer, s io.ReadSeeker) (*seekableBody, error) {
+	pos, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	b := &seekableBody{body: body, s: s, pos: pos}
+
+	if ra, ok := s.(io.ReaderAt); ok {
+		size, err := s.Seek(0, io.SeekEnd)
+		if err == nil {
+			_, err = s.Seek(pos, io.SeekStart)
+		}
+		if err != nil {
+			return nil, err
+		}
+		b.ra, b.size = ra, size
+	}
+
+	return b, nil
+}
+
+// next returns a reader for the next attempt.
+// Bodies implementing io.ReaderAt are read with independent section readers.
+// Other bodies are rewound on the first read after the reader of the previous attempt is closed.
+func (b *seekableBody) next() io.ReadCloser {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.open++
+	closed := make(chan struct{})
+
+	var r io.Reader
+	switch {
+	case b.ra != nil:
+		r = io.NewSectionReader(b.ra, b.pos, b.size-b.pos)
+	case b.prevClosed == nil:
+		r = b.s
+	default:
+		r = &rewindReader{s: b.s, pos: b.pos, wait: b.prevClosed}
+	}
+	b.prevClosed = closed
+
+	return &attemptBody{Reader: r, closed: closed, release: b.release}
+}
+
+func (b *seekableBody) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.open--
+	if b.done && b.open == 0 {
+		_ = b.body.Close()
+	}
+}
+
+// finish is called after all attempts. The original body is closed when all readers are closed.
+func (b *seekableBody) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done = true
+	if b.open == 0 {
+		_ = b.body.Close()
+	}
+}
+
+type attemptBody struct {
+	io.Reader
+	closed  chan struct{}
+	release func()
+	once    sync.Once
+}
+
+func (b *attemptBody) Close() error {
+	b.once.Do(func() {
+		close(b.closed)
+		b.release()
+	})
+	return nil
+}
+
+// rewindReader seeks to pos on the first read after wait is closed.
+type rewindReader struct {
+	s       io.ReadSeeker
+	pos     int64
+	wait    <-chan struct{}
+	rewound bool
+}
+
+func (r *rewindReader) Read(p []byte) (int, error) {
+	if !r.rewound {
+		<-r.wait
+		_, err := r.s.Seek(r.pos, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+		r.rewound = true
+	}
+	return r.s.Read(p)
+}
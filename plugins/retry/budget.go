@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrBudgetExhausted is returned when a request should be retried but the retry budget has no tokens.
+var ErrBudgetExhausted = errors.New("retry: retry budget exhausted")
+
+// Budget is a token bucket that limits retries across requests to avoid amplifying load on failing servers.
+// Every request deposits ratio tokens, and every retry withdraws one token.
+// It should be shared between requests.
+//  // allow retries up to 10% of requests, and bursts of 100 retries.
+//  budget := retry.NewBudget(0.1, 100)
+//
+//  err := hx.Get(ctx, "https://api.example.com/contents/1",
+//  	retry.When(hx.IsServerError, bo, retry.WithBudget(budget)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+type Budget struct {
+	mu     sync.Mutex
+	ratio  float64
+	max    float64
+	tokens float64
+}
+
+// NewBudget creates a Budget that has max tokens initially.
+func NewBudget(ratio float64, max int) *Budget {
+	return &Budget{ratio: ratio, max: float64(max), tokens: float64(max)}
+}
+
+// Tokens returns the number of remaining tokens.
+func (b *Budget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+func (b *Budget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+func (b *Budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,49 @@
+package retry
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ExhaustedError is returned when a request is still failing but no more retries are allowed,
+// because of MaxAttempts, MaxElapsedTime, the backoff or the request context.
+//  var exhausted *retry.ExhaustedError
+//  if errors.As(err, &exhausted) {
+//  	log.Printf("failed after %d attempts in %v", len(exhausted.Attempts), exhausted.Elapsed)
+//  }
+type ExhaustedError struct {
+	// Attempts holds results of all failed attempts in order.
+	Attempts []Attempt
+	// Elapsed is the total duration from the first attempt.
+	Elapsed time.Duration
+	// Response is the response of the last attempt if exists.
+	// Its body has been closed to release the connection, but the first 64 KiB of the body is kept in memory and can be read.
+	// Response handlers can be applied to it to decode the error body:
+	//  _, err = hx.AsJSONError(&APIError{})(exhausted.Response, nil)
+	Response *http.Response
+	// Err is the error of the last attempt, the context error when the request is canceled while waiting for the next attempt,
+	// ErrBudgetExhausted, or the error on rewinding the request body (e.g. ErrBodyTooLarge).
+	Err error
+}
+
+// Attempt is a result of a failed attempt.
+type Attempt struct {
+	// StatusCode is zero when the attempt does not receive any response.
+	StatusCode int
+	Err        error
+}
+
+func (e *ExhaustedError) Error() string {
+	msg := fmt.Sprintf("retry: gave up after %d attempts in %v", len(e.Attempts), e.Elapsed)
+	switch {
+	case e.Err != nil:
+		return msg + ": " + e.Err.Error()
+	case e.Response != nil:
+		return msg + ": " + e.Response.Status
+	default:
+		return msg
+	}
+}
+
+func (e *ExhaustedError) Unwrap() error { return e.Err }
@@ -0,0 +1,11 @@
+package retry
+
+import "time"
+
+var ParseRetryAfter = parseRetryAfter
+
+func SetNow(f func() time.Time) func() {
+	tmp := now
+	now = f
+	return func() { now = tmp }
+}
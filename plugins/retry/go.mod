@@ -1,6 +1,8 @@
 module github.com/izumin5210/hx/plugins/retry
 
-go 1.13
+go 1.18
+
+replace github.com/izumin5210/hx => ../../
 
 require (
 	github.com/cenkalti/backoff/v3 v3.0.0
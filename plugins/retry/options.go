@@ -2,9 +2,12 @@
 package retry
 
 import (
+	"math/rand"
 	"net/http"
+	"time"
 
 	"github.com/cenkalti/backoff/v3"
+	"github.com/google/uuid"
 	"github.com/izumin5210/hx"
 )
 
@@ -14,13 +17,156 @@ import (
 //  bo.MaxInterval = 500 * time.Millisecond
 //
 //  err := hx.Post(ctx, "https://example.com/api/messages",
-//  	retry.When(hx.Any(hx.IsServerError(), hx.IsTemporaryError()), bo),
+//  	retry.When(hx.Any(hx.IsServerError(), hx.IsTemporaryError()), bo,
+//  		retry.MaxAttempts(5),
+//  		retry.RespectRetryAfter(30*time.Second),
+//  		// POST requests are retried with Idempotency-Key
+//  		retry.RetryNonIdempotent(),
+//  	),
 //  	hx.JSON(&in),
 //  	hx.WhenSuccess(hx.AsJSON(&out)),
 //  	hx.WhenFailure(hx.AsError()),
 //  )
-func When(cond hx.ResponseHandlerCond, bo backoff.BackOff) hx.Option {
+func When(cond hx.ResponseHandlerCond, bo backoff.BackOff, opts ...Option) hx.Option {
+	return WhenAttempt(ignoreAttempt(cond), bo, opts...)
+}
+
+// WhenAttempt is the same as When, but the condition receives the number of attempts that have been made.
+//  // retry server errors 3 times, but retry rate-limited requests until the backoff stops
+//  retry.WhenAttempt(func(attempt int, r *http.Response, err error) bool {
+//  	if hx.IsStatus(http.StatusTooManyRequests)(r, err) {
+//  		return true
+//  	}
+//  	return attempt < 3 && hx.IsServerError(r, err)
+//  }, bo, retry.RespectRetryAfter(time.Minute))
+func WhenAttempt(cond Cond, bo backoff.BackOff, opts ...Option) hx.Option {
 	return hx.TransportFrom(func(t http.RoundTripper) http.RoundTripper {
-		return NewTransport(t, cond, bo)
+		return NewAttemptTransport(t, cond, bo, opts...)
 	})
 }
+
+// Cond decides whether a request should be retried or not.
+// attempt is the number of attempts that have been made, starting from 1.
+type Cond func(attempt int, r *http.Response, err error) bool
+
+func ignoreAttempt(cond hx.ResponseHandlerCond) Cond {
+	return func(_ int, r *http.Response, err error) bool { return cond(r, err) }
+}
+
+// Option configures retry behaviors.
+type Option func(*config)
+
+type config struct {
+	maxAttempts       int
+	maxElapsedTime    time.Duration
+	respectRetryAfter bool
+	maxRetryAfter     time.Duration
+	jitter            Jitter
+
+	retryNonIdempotent   bool
+	idempotencyKeyHeader string
+	idempotencyKeyFunc   func() string
+	injectIdempotencyKey bool
+
+	maxBufferSize int64
+
+	budget *Budget
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		jitter:               NoJitter,
+		idempotencyKeyHeader: DefaultIdempotencyKeyHeader,
+		idempotencyKeyFunc:   func() string { return uuid.New().String() },
+		injectIdempotencyKey: true,
+		maxBufferSize:        DefaultMaxBufferSize,
+	}
+	for _, f := range opts {
+		f(c)
+	}
+	return c
+}
+
+// MaxAttempts limits the number of attempts including the first request. It is unlimited if n <= 0.
+func MaxAttempts(n int) Option {
+	return func(c *config) { c.maxAttempts = n }
+}
+
+// MaxElapsedTime stops retrying when the next attempt would start after d has elapsed since the first request.
+// It is unlimited if d <= 0.
+func MaxElapsedTime(d time.Duration) Option {
+	return func(c *config) { c.maxElapsedTime = d }
+}
+
+// RespectRetryAfter waits the duration given by Retry-After header if it is longer than the backoff interval.
+// Both delay-seconds and HTTP-date forms are supported, and the wait is capped by max (unlimited if max <= 0).
+func RespectRetryAfter(max time.Duration) Option {
+	return func(c *config) {
+		c.respectRetryAfter = true
+		c.maxRetryAfter = max
+	}
+}
+
+// WithJitter randomizes intervals returned from the backoff.
+func WithJitter(j Jitter) Option {
+	return func(c *config) { c.jitter = j }
+}
+
+// MaxBufferSize limits the size of request bodies buffered in memory for retries. It is 1 MiB in default.
+// Bodies are not buffered if requests have GetBody or the bodies implement io.Seeker.
+// Requests with larger bodies are sent only once, and fail with ErrBodyTooLarge when they should be retried.
+func MaxBufferSize(n int64) Option {
+	return func(c *config) { c.maxBufferSize = n }
+}
+
+// WithBudget limits retries by a given retry budget.
+// Requests fail with ErrBudgetExhausted when they should be retried but the budget has no tokens.
+func WithBudget(b *Budget) Option {
+	return func(c *config) { c.budget = b }
+}
+
+// RetryNonIdempotent allows retrying requests with non-idempotent methods (e.g. POST, PATCH) even if callers give no idempotency keys.
+// Generated keys are injected into such requests unless WithoutIdempotencyKey is specified.
+func RetryNonIdempotent() Option {
+	return func(c *config) { c.retryNonIdempotent = true }
+}
+
+// IdempotencyKeyHeader changes the header name for idempotency keys. It is "Idempotency-Key" in default.
+func IdempotencyKeyHeader(name string) Option {
+	return func(c *config) { c.idempotencyKeyHeader = http.CanonicalHeaderKey(name) }
+}
+
+// IdempotencyKeyFunc changes the generator of idempotency keys. UUIDv4 is used in default.
+func IdempotencyKeyFunc(f func() string) Option {
+	return func(c *config) { c.idempotencyKeyFunc = f }
+}
+
+// WithoutIdempotencyKey disables injecting idempotency keys into requests retried by RetryNonIdempotent.
+func WithoutIdempotencyKey() Option {
+	return func(c *config) { c.injectIdempotencyKey = false }
+}
+
+// Jitter returns a randomized interval from an interval calculated by the backoff.
+type Jitter func(d time.Duration) time.Duration
+
+var (
+	// NoJitter uses intervals as is.
+	NoJitter Jitter = func(d time.Duration) time.Duration { return d }
+
+	// FullJitter returns a random duration in [0, d).
+	FullJitter Jitter = func(d time.Duration) time.Duration {
+		if d <= 0 {
+			return 0
+		}
+		return time.Duration(rand.Int63n(int64(d)))
+	}
+
+	// EqualJitter returns a random duration in [d/2, d).
+	EqualJitter Jitter = func(d time.Duration) time.Duration {
+		if d <= 1 {
+			return d
+		}
+		half := d / 2
+		return half + time.Duration(rand.Int63n(int64(d-half)))
+	}
+)
@@ -1,11 +1,20 @@
 package retry_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -60,7 +69,7 @@ func TestRetry(t *testing.T) {
 	var out Message
 
 	err := hx.Post(context.Background(), ts.URL+"/messages",
-		retry.When(hx.Any(hx.IsServerError, hx.IsTemporaryError), bo),
+		retry.When(hx.Any(hx.IsServerError, hx.IsTemporaryError), bo, retry.RetryNonIdempotent()),
 		hx.JSON(&in),
 		hx.WhenSuccess(hx.AsJSON(&out)),
 		hx.WhenFailure(hx.AsError()),
@@ -77,3 +86,483 @@ func TestRetry(t *testing.T) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 }
+
+func TestRetry_Options(t *testing.T) {
+	var cnt int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&cnt, 1)
+		switch r.URL.Path {
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/rate_limited":
+			if atomic.LoadInt32(&cnt) == 1 {
+				w.Header().Set("Retry-After", "120")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	retryable := hx.IsStatus(http.StatusTooManyRequests, http.StatusServiceUnavailable)
+
+	cases := []struct {
+		test      string
+		path      string
+		opt       hx.Option
+		attempts  int32
+		minTime   time.Duration
+		exhausted bool
+	}{
+		{
+			test:      "max attempts",
+			path:      "/unavailable",
+			opt:       retry.When(retryable, backoff.NewConstantBackOff(time.Millisecond), retry.MaxAttempts(3)),
+			attempts:  3,
+			exhausted: true,
+		},
+		{
+			test:      "max elapsed time",
+			path:      "/unavailable",
+			opt:       retry.When(retryable, backoff.NewConstantBackOff(100*time.Millisecond), retry.MaxElapsedTime(250*time.Millisecond)),
+			attempts:  3,
+			exhausted: true,
+			minTime:   200 * time.Millisecond,
+		},
+		{
+			test:      "backoff stops",
+			path:      "/unavailable",
+			opt:       retry.When(retryable, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Millisecond), 2)),
+			attempts:  3,
+			exhausted: true,
+		},
+		{
+			test: "attempt-aware condition",
+			path: "/unavailable",
+			opt: retry.WhenAttempt(func(attempt int, r *http.Response, err error) bool {
+				return attempt < 2 && retryable(r, err)
+			}, backoff.NewConstantBackOff(time.Millisecond)),
+			attempts: 2,
+		},
+		{
+			test:     "respect Retry-After with cap",
+			path:     "/rate_limited",
+			opt:      retry.When(retryable, backoff.NewConstantBackOff(time.Millisecond), retry.RespectRetryAfter(100*time.Millisecond)),
+			attempts: 2,
+			minTime:  100 * time.Millisecond,
+		},
+		{
+			test: "jitter",
+			path: "/unavailable",
+			opt: retry.When(retryable, backoff.NewConstantBackOff(10*time.Millisecond),
+				retry.MaxAttempts(4), retry.WithJitter(retry.FullJitter)),
+			attempts:  4,
+			exhausted: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			atomic.StoreInt32(&cnt, 0)
+			startedAt := time.Now()
+
+			err := hx.Get(context.Background(), ts.URL+tc.path, tc.opt)
+			if tc.exhausted {
+				var exhausted *retry.ExhaustedError
+				if !errors.As(err, &exhausted) {
+					t.Fatalf("returned %v, want *retry.ExhaustedError", err)
+				}
+				if got, want := len(exhausted.Attempts), int(tc.attempts); got != want {
+					t.Errorf("ExhaustedError has %d attempts, want %d", got, want)
+				}
+				for _, a := range exhausted.Attempts {
+					if got, want := a.StatusCode, http.StatusServiceUnavailable; got != want {
+						t.Errorf("attempt has status %d, want %d", got, want)
+					}
+				}
+				if got, want := exhausted.Response.StatusCode, http.StatusServiceUnavailable; got != want {
+					t.Errorf("ExhaustedError has a response with status %d, want %d", got, want)
+				}
+			} else if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+
+			if got, want := atomic.LoadInt32(&cnt), tc.attempts; got != want {
+				t.Errorf("sent %d requests, want %d", got, want)
+			}
+			if got, want := time.Since(startedAt), tc.minTime; got < want {
+				t.Errorf("took %v, want >= %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRetry_CloseDiscardedResponses(t *testing.T) {
+	var bodies []*trackedBody
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b := &trackedBody{Reader: strings.NewReader("unavailable")}
+		bodies = append(bodies, b)
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Body: b, Request: req}, nil
+	})
+
+	err := hx.Get(context.Background(), "https://api.example.com/",
+		hx.Transport(rt),
+		retry.When(hx.IsServerError, backoff.NewConstantBackOff(time.Millisecond), retry.MaxAttempts(3)),
+	)
+
+	var exhausted *retry.ExhaustedError
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("returned %v, want *retry.ExhaustedError", err)
+	}
+	if got, want := len(bodies), 3; got != want {
+		t.Fatalf("sent %d requests, want %d", got, want)
+	}
+	for i, b := range bodies {
+		if !b.closed {
+			t.Errorf("body of attempt %d is not closed", i+1)
+		}
+	}
+	if got, want := exhausted.Error(), "retry: gave up after 3 attempts"; !strings.HasPrefix(got, want) {
+		t.Errorf("Error() returned %q, want prefix %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	_, err = hx.AsBytesBuffer(&buf)(exhausted.Response, nil)
+	if err != nil {
+		t.Errorf("reading the last response returned %v, want nil", err)
+	}
+	if got, want := buf.String(), "unavailable"; got != want {
+		t.Errorf("the last response has body %q, want %q", got, want)
+	}
+}
+
+func TestRetry_ExhaustedWithError(t *testing.T) {
+	errNet := errors.New("connection refused")
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errNet
+	})
+
+	err := hx.Get(context.Background(), "https://api.example.com/",
+		hx.Transport(rt),
+		retry.When(func(_ *http.Response, err error) bool { return err != nil },
+			backoff.NewConstantBackOff(time.Millisecond), retry.MaxAttempts(2)),
+	)
+
+	var exhausted *retry.ExhaustedError
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("returned %v, want *retry.ExhaustedError", err)
+	}
+	if !errors.Is(err, errNet) {
+		t.Errorf("returned %v, want to wrap %v", err, errNet)
+	}
+	if got, want := exhausted.Attempts, []retry.Attempt{{Err: errNet}, {Err: errNet}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExhaustedError has %v, want %v", got, want)
+	}
+}
+
+func TestRetry_IdempotencyPolicy(t *testing.T) {
+	cases := []struct {
+		test     string
+		method   string
+		opts     []retry.Option
+		header   http.Header
+		attempts int
+		key      string
+	}{
+		{
+			test:     "GET is retried without key",
+			method:   http.MethodGet,
+			attempts: 3,
+		},
+		{
+			test:     "PUT is retried without key",
+			method:   http.MethodPut,
+			attempts: 3,
+		},
+		{
+			test:     "POST is not retried in default",
+			method:   http.MethodPost,
+			opts:     []retry.Option{retry.IdempotencyKeyFunc(func() string { return "generated" })},
+			attempts: 1,
+		},
+		{
+			test:     "POST is retried with injected key when opted in",
+			method:   http.MethodPost,
+			opts:     []retry.Option{retry.RetryNonIdempotent(), retry.IdempotencyKeyFunc(func() string { return "generated" })},
+			attempts: 3,
+			key:      "generated",
+		},
+		{
+			test:     "POST is not retried without key",
+			method:   http.MethodPost,
+			opts:     []retry.Option{retry.WithoutIdempotencyKey()},
+			attempts: 1,
+		},
+		{
+			test:     "POST is retried with a key given by callers",
+			method:   http.MethodPost,
+			header:   http.Header{"Idempotency-Key": {"given"}},
+			attempts: 3,
+			key:      "given",
+		},
+		{
+			test:     "PATCH is retried when opted in",
+			method:   http.MethodPatch,
+			opts:     []retry.Option{retry.WithoutIdempotencyKey(), retry.RetryNonIdempotent()},
+			attempts: 3,
+		},
+		{
+			test:   "custom header name",
+			method: http.MethodPost,
+			opts: []retry.Option{
+				retry.RetryNonIdempotent(),
+				retry.IdempotencyKeyHeader("X-Request-Id"),
+				retry.IdempotencyKeyFunc(func() string { return "generated" }),
+			},
+			attempts: 3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			var reqs []*http.Request
+			rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				reqs = append(reqs, req)
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: req}, nil
+			})
+
+			opts := []hx.Option{
+				hx.Transport(rt),
+				retry.When(hx.IsServerError, backoff.NewConstantBackOff(time.Millisecond), append(tc.opts, retry.MaxAttempts(3))...),
+			}
+			for k, vs := range tc.header {
+				opts = append(opts, hx.Header(k, vs[0]))
+			}
+			_ = hx.Request(context.Background(), tc.method, "https://api.example.com/", opts...)
+
+			if got, want := len(reqs), tc.attempts; got != want {
+				t.Errorf("sent %d requests, want %d", got, want)
+			}
+			for _, req := range reqs {
+				if got, want := req.Header.Get("Idempotency-Key"), tc.key; got != want {
+					t.Errorf("Idempotency-Key is %q, want %q", got, want)
+				}
+			}
+			if tc.test == "custom header name" {
+				if got, want := reqs[0].Header.Get("X-Request-Id"), "generated"; got != want {
+					t.Errorf("X-Request-Id is %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRetry_RequestBody(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	err := os.WriteFile(path, []byte("file data"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write a file: %v", err)
+	}
+
+	cases := []struct {
+		test     string
+		body     func(t *testing.T) hx.Option
+		opts     []retry.Option
+		want     []string
+		tooLarge bool
+	}{
+		{
+			test: "GetBody",
+			body: func(t *testing.T) hx.Option { return hx.Body("hello") },
+			want: []string{"hello", "hello", "hello"},
+		},
+		{
+			test: "GetBody from hx",
+			body: func(t *testing.T) hx.Option { return hx.Multipart(hx.MultipartField("title", "hello")) },
+			want: []string{"title=hello", "title=hello", "title=hello"},
+		},
+		{
+			test: "seeker",
+			body: func(t *testing.T) hx.Option {
+				f, err := os.Open(path)
+				if err != nil {
+					t.Fatalf("failed to open a file: %v", err)
+				}
+				return hx.Body(f)
+			},
+			opts: []retry.Option{retry.MaxBufferSize(0)},
+			want: []string{"file data", "file data", "file data"},
+		},
+		{
+			test: "seeker without ReaderAt",
+			body: func(t *testing.T) hx.Option {
+				f, err := os.Open(path)
+				if err != nil {
+					t.Fatalf("failed to open a file: %v", err)
+				}
+				return hx.Body(struct{ io.ReadSeekCloser }{f})
+			},
+			opts: []retry.Option{retry.MaxBufferSize(0)},
+			want: []string{"file data", "file data", "file data"},
+		},
+		{
+			test: "buffered",
+			body: func(t *testing.T) hx.Option { return hx.Body(ioutil.NopCloser(strings.NewReader("hello"))) },
+			opts: []retry.Option{retry.MaxBufferSize(5)},
+			want: []string{"hello", "hello", "hello"},
+		},
+		{
+			test:     "too large",
+			body:     func(t *testing.T) hx.Option { return hx.Body(ioutil.NopCloser(strings.NewReader("hello"))) },
+			opts:     []retry.Option{retry.MaxBufferSize(4)},
+			want:     []string{"hello"},
+			tooLarge: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			var got []string
+			rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				var body string
+				if mt, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mt == "multipart/form-data" {
+					err := req.ParseMultipartForm(1 << 10)
+					if err != nil {
+						t.Errorf("failed to parse a multipart body: %v", err)
+					}
+					body = req.MultipartForm.Value["title"][0]
+					body = "title=" + body
+				} else {
+					data, err := ioutil.ReadAll(req.Body)
+					if err != nil {
+						t.Errorf("failed to read a body: %v", err)
+					}
+					body = string(data)
+				}
+				req.Body.Close()
+				got = append(got, body)
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: req}, nil
+			})
+
+			err := hx.Put(context.Background(), "https://api.example.com/",
+				hx.Transport(rt),
+				tc.body(t),
+				retry.When(hx.IsServerError, backoff.NewConstantBackOff(time.Millisecond), append(tc.opts, retry.MaxAttempts(3))...),
+			)
+
+			if got, want := errors.Is(err, retry.ErrBodyTooLarge), tc.tooLarge; got != want {
+				t.Errorf("returned %v, want ErrBodyTooLarge: %t", err, want)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("sent %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRetry_Budget(t *testing.T) {
+	var cnt int
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		cnt++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: req}, nil
+	})
+
+	budget := retry.NewBudget(0.5, 2)
+
+	get := func() error {
+		return hx.Get(context.Background(), "https://api.example.com/",
+			hx.Transport(rt),
+			retry.When(hx.IsServerError, backoff.NewConstantBackOff(time.Millisecond), retry.MaxAttempts(3), retry.WithBudget(budget)),
+		)
+	}
+
+	// 2 tokens: 2 retries are allowed
+	err := get()
+	if got, want := cnt, 3; got != want {
+		t.Errorf("sent %d requests, want %d", got, want)
+	}
+	if errors.Is(err, retry.ErrBudgetExhausted) {
+		t.Errorf("returned %v, want not ErrBudgetExhausted", err)
+	}
+
+	// 0.5 tokens: no retries are allowed
+	cnt = 0
+	err = get()
+	if got, want := cnt, 1; got != want {
+		t.Errorf("sent %d requests, want %d", got, want)
+	}
+	if !errors.Is(err, retry.ErrBudgetExhausted) {
+		t.Errorf("returned %v, want ErrBudgetExhausted", err)
+	}
+
+	// 1 token: 1 retry is allowed
+	cnt = 0
+	_ = get()
+	if got, want := cnt, 2; got != want {
+		t.Errorf("sent %d requests, want %d", got, want)
+	}
+	if got, want := budget.Tokens(), 0.0; got != want {
+		t.Errorf("Tokens() returned %v, want %v", got, want)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	defer retry.SetNow(func() time.Time { return now })()
+
+	cases := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{in: "120", want: 2 * time.Minute, ok: true},
+		{in: "0", want: 0, ok: true},
+		{in: "Wed, 21 Oct 2015 07:28:30 GMT", want: 30 * time.Second, ok: true},
+		{in: "Wed, 21 Oct 2015 07:27:00 GMT", want: 0, ok: true},
+		{in: "", ok: false},
+		{in: "-1", ok: false},
+		{in: "soon", ok: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, ok := retry.ParseRetryAfter(tc.in)
+			if ok != tc.ok {
+				t.Errorf("returned %t, want %t", ok, tc.ok)
+			}
+			if got != tc.want {
+				t.Errorf("returned %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJitter(t *testing.T) {
+	d := 100 * time.Millisecond
+	for i := 0; i < 100; i++ {
+		if got := retry.FullJitter(d); got < 0 || got >= d {
+			t.Errorf("FullJitter returned %v, want [0, %v)", got, d)
+		}
+		if got := retry.EqualJitter(d); got < d/2 || got >= d {
+			t.Errorf("EqualJitter returned %v, want [%v, %v)", got, d/2, d)
+		}
+	}
+}
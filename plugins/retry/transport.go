@@ -2,19 +2,28 @@ package retry
 
 import (
 	"bytes"
-	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/cenkalti/backoff/v3"
-	"github.com/google/uuid"
 	"github.com/izumin5210/hx"
 )
 
+var now = time.Now
+
+// DefaultIdempotencyKeyHeader is the default header name for idempotency keys.
+const DefaultIdempotencyKeyHeader = "Idempotency-Key"
+
+const maxDiscardBytes = 64 << 10
+
 type Transport struct {
 	parent http.RoundTripper
-	cond   hx.ResponseHandlerCond
+	cond   Cond
 	bo     backoff.BackOff
+	cfg    *config
 }
 
 var _ http.RoundTripper = (*Transport)(nil)
@@ -23,51 +32,209 @@ func NewTransport(
 	parent http.RoundTripper,
 	cond hx.ResponseHandlerCond,
 	bo backoff.BackOff,
+	opts ...Option,
+) *Transport {
+	return NewAttemptTransport(parent, ignoreAttempt(cond), bo, opts...)
+}
+
+// NewAttemptTransport is the same as NewTransport, but the condition receives the number of attempts.
+func NewAttemptTransport(
+	parent http.RoundTripper,
+	cond Cond,
+	bo backoff.BackOff,
+	opts ...Option,
 ) *Transport {
 	return &Transport{
 		parent: parent,
 		cond:   cond,
 		bo:     bo,
+		cfg:    newConfig(opts),
 	}
 }
 
 func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
-	bo := backoff.WithContext(t.bo, req.Context())
+	ctx := req.Context()
+	bo := backoff.WithContext(t.bo, ctx)
 	bo.Reset()
 
-	if req.Body != nil {
-		var buf bytes.Buffer
-		_, err := buf.ReadFrom(req.Body)
-		if err != nil {
-			return nil, err
+	next := t.parent
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	// decide with headers given by callers, before injecting an idempotency key
+	if !t.cfg.isRetryable(req) {
+		return next.RoundTrip(req)
+	}
+	req = t.cfg.setIdempotencyKey(req)
+
+	body, rewind, cleanup, err := newRewinder(req, t.cfg.maxBufferSize)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	r := *req
+	req = &r
+	req.Body = body
+
+	startedAt := now()
+	var attempts []Attempt
+
+	exhausted := func(err error) (*http.Response, error) {
+		e := &ExhaustedError{Attempts: attempts, Elapsed: now().Sub(startedAt), Err: err}
+		if resp != nil {
+			bufferResponseBody(resp)
+			e.Response = resp
+		}
+		return nil, e
+	}
+
+	if b := t.cfg.budget; b != nil {
+		b.deposit()
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err = next.RoundTrip(req)
+		if !t.cond(attempt, resp, err) {
+			return
+		}
+
+		a := Attempt{Err: err}
+		if resp != nil {
+			a.StatusCode = resp.StatusCode
+		}
+		attempts = append(attempts, a)
+
+		if t.cfg.maxAttempts > 0 && attempt >= t.cfg.maxAttempts {
+			return exhausted(err)
+		}
+
+		wait, ok := t.nextInterval(bo, resp)
+		if !ok {
+			return exhausted(err)
 		}
-		err = req.Body.Close()
-		if err != nil {
-			return nil, err
+		if t.cfg.maxElapsedTime > 0 && now().Add(wait).Sub(startedAt) > t.cfg.maxElapsedTime {
+			return exhausted(err)
 		}
-		req.Body = ioutil.NopCloser(&buf)
+
+		if b := t.cfg.budget; b != nil && !b.withdraw() {
+			return exhausted(ErrBudgetExhausted)
+		}
+
+		body, rerr := rewind()
+		if rerr != nil {
+			return exhausted(rerr)
+		}
+
+		// the response will not be returned to callers, so release its connection before the next attempt.
+		if resp != nil {
+			discardResponseBody(resp)
+			resp = nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if body != nil {
+				_ = body.Close()
+			}
+			return exhausted(ctx.Err())
+		case <-timer.C:
+		}
+		req.Body = body
 	}
+}
 
-	setIdempotencyKey(req)
+func (t *Transport) nextInterval(bo backoff.BackOff, resp *http.Response) (time.Duration, bool) {
+	d := bo.NextBackOff()
+	if d == backoff.Stop {
+		return 0, false
+	}
+	d = t.cfg.jitter(d)
 
-	next := t.parent
-	if next == nil {
-		next = http.DefaultTransport
+	if t.cfg.respectRetryAfter && resp != nil {
+		if ra, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if t.cfg.maxRetryAfter > 0 && ra > t.cfg.maxRetryAfter {
+				ra = t.cfg.maxRetryAfter
+			}
+			if ra > d {
+				d = ra
+			}
+		}
 	}
 
-	_ = backoff.Retry(func() error {
-		resp, err = next.RoundTrip(req)
-		if t.cond(resp, err) {
-			return errors.New("retry")
+	return d, true
+}
+
+// parseRetryAfter parses a value of Retry-After header.
+// It accepts both delay-seconds and HTTP-date forms (RFC 7231 Section 7.1.3).
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now())
+		if d < 0 {
+			d = 0
 		}
-		return nil
-	}, bo)
+		return d, true
+	}
+	return 0, false
+}
+
+// discardResponseBody reads a small amount of the body so that the connection can be reused, and closes it.
+func discardResponseBody(r *http.Response) {
+	if r.Body == nil {
+		return
+	}
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(r.Body, maxDiscardBytes))
+	_ = r.Body.Close()
+}
+
+// bufferResponseBody reads up to maxDiscardBytes of the body into memory and closes it,
+// so that the body is readable after the connection is released.
+// The rest of the body is dropped.
+func bufferResponseBody(r *http.Response) {
+	if r.Body == nil {
+		return
+	}
+	data, _ := ioutil.ReadAll(io.LimitReader(r.Body, maxDiscardBytes))
+	_ = r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+}
+
+// setIdempotencyKey injects an idempotency key into a retryable request with a non-idempotent method,
+// that is, a request allowed by RetryNonIdempotent. Requests with idempotent methods are safe to retry without keys.
+// It returns a shallow copy of the request when it sets the key, since RoundTrippers should not modify requests.
+func (c *config) setIdempotencyKey(r *http.Request) *http.Request {
+	if !c.injectIdempotencyKey || isIdempotent(r.Method) || r.Header.Get(c.idempotencyKeyHeader) != "" {
+		return r
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set(c.idempotencyKeyHeader, c.idempotencyKeyFunc())
+	return r
+}
 
-	return
+// isRetryable reports whether a request can be retried.
+// Requests with non-idempotent methods are retried only if callers give idempotency keys or RetryNonIdempotent is specified.
+func (c *config) isRetryable(r *http.Request) bool {
+	return c.retryNonIdempotent || isIdempotent(r.Method) || r.Header.Get(c.idempotencyKeyHeader) != ""
 }
 
-func setIdempotencyKey(r *http.Request) {
-	if r.Header.Get("Idempotency-Key") == "" {
-		r.Header.Set("Idempotency-Key", uuid.New().String())
+// isIdempotent reports whether a method is idempotent (RFC 7231 Section 4.2.2).
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
 	}
 }
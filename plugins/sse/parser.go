@@ -0,0 +1,119 @@
+package sse
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parser reads events from text/event-stream.
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+type parser struct {
+	r *bufio.Reader
+	// lastEventID is updated with idBuffer when a block ends, even if the block has no data.
+	lastEventID string
+	idBuffer    string
+	retry       time.Duration
+	started     bool
+	skipLF      bool
+}
+
+func newParser(r io.Reader, lastEventID string) *parser {
+	return &parser{r: bufio.NewReader(r), lastEventID: lastEventID, idBuffer: lastEventID}
+}
+
+// Next reads lines until an event is dispatched.
+func (p *parser) Next() (*Event, error) {
+	var (
+		data      strings.Builder
+		eventType string
+		retry     time.Duration
+	)
+
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if line == "" {
+			p.lastEventID = p.idBuffer
+			if data.Len() == 0 {
+				eventType, retry = "", 0
+				continue
+			}
+			if eventType == "" {
+				eventType = "message"
+			}
+			return &Event{
+				ID:    p.lastEventID,
+				Event: eventType,
+				Data:  strings.TrimSuffix(data.String(), "\n"),
+				Retry: retry,
+			}, nil
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				p.idBuffer = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+				p.retry = retry
+			}
+		}
+	}
+}
+
+// readLine reads a line terminated by CRLF, LF or CR.
+func (p *parser) readLine() (string, error) {
+	var b strings.Builder
+	for {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if p.skipLF {
+			p.skipLF = false
+			if c == '\n' {
+				continue
+			}
+		}
+		switch c {
+		case '\n':
+			return p.trimBOM(b.String()), nil
+		case '\r':
+			// do not wait for the next byte to dispatch events immediately
+			p.skipLF = true
+			return p.trimBOM(b.String()), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *parser) trimBOM(line string) string {
+	if p.started {
+		return line
+	}
+	p.started = true
+	return strings.TrimPrefix(line, "\uFEFF")
+}
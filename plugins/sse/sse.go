@@ -0,0 +1,204 @@
+// A plugin for receiving Server-Sent Events.
+package sse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/izumin5210/hx"
+)
+
+var (
+	DefaultConfig = &Config{}
+
+	// ErrUnexpectedContentType is returned when the server responds with a content type other than text/event-stream.
+	ErrUnexpectedContentType = errors.New("sse: unexpected content type")
+)
+
+const defaultRetryInterval = 3 * time.Second
+
+// Event is a message dispatched from event streams.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	// Retry is the reconnection time that the server sent with the event.
+	Retry time.Duration
+}
+
+// Subscribe connects to an event stream and calls a given callback with each event.
+// It reconnects with Last-Event-ID header automatically when the connection is lost.
+// It returns when the context is canceled, the callback returns an error, the server responds with non-2xx status or 204 No Content.
+//  err := sse.Subscribe(ctx, "https://api.example.com/builds/1/logs", func(ev *sse.Event) error {
+//  	// handle the event...
+//  	return nil
+//  },
+//  	hx.Bearer(token),
+//  	hx.TransportFrom(hxzap.New().Wrap),
+//  )
+func Subscribe(ctx context.Context, url string, f func(*Event) error, opts ...hx.Option) error {
+	return DefaultConfig.Subscribe(ctx, url, f, opts...)
+}
+
+// Events is the same as Subscribe, but it delivers events via a channel.
+// The error channel receives the result of the subscription once, and then both channels are closed.
+// Callers must cancel the context when they stop receiving events before the channels are closed.
+// Otherwise, the goroutine sending events is blocked forever.
+func Events(ctx context.Context, url string, opts ...hx.Option) (<-chan *Event, <-chan error) {
+	return DefaultConfig.Events(ctx, url, opts...)
+}
+
+type Config struct {
+	// Client is used to send requests. hx.NewClient() is used if nil.
+	Client *hx.Client
+	// RetryInterval is the reconnection time used until the server sends the retry field. It is 3 seconds in default.
+	RetryInterval time.Duration
+	// MaxRetries is the max number of consecutive reconnections without receiving events. It is unlimited if zero.
+	MaxRetries int
+}
+
+func (c *Config) Subscribe(ctx context.Context, url string, f func(*Event) error, opts ...hx.Option) error {
+	cli := c.Client
+	if cli == nil {
+		cli = hx.NewClient()
+	}
+
+	s := &stream{f: f, retry: c.RetryInterval}
+	if s.retry <= 0 {
+		s.retry = defaultRetryInterval
+	}
+
+	for retries := 0; ; retries++ {
+		s.received = false
+
+		err := cli.Get(ctx, url, s.options(opts)...)
+		if s.received {
+			retries = 0
+		}
+
+		if cbErr, ok := err.(*callbackError); ok {
+			return cbErr.err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if s.closed {
+			return nil
+		}
+		// non-2xx status or unexpected content type
+		var respErr *hx.ResponseError
+		if errors.As(err, &respErr) {
+			return err
+		}
+		if c.MaxRetries > 0 && retries >= c.MaxRetries {
+			if err == nil {
+				err = errors.New("sse: the connection was closed")
+			}
+			return err
+		}
+
+		t := time.NewTimer(s.retry)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (c *Config) Events(ctx context.Context, url string, opts ...hx.Option) (<-chan *Event, <-chan error) {
+	evCh := make(chan *Event)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(errCh)
+		defer close(evCh)
+
+		errCh <- c.Subscribe(ctx, url, func(ev *Event) error {
+			select {
+			case evCh <- ev:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, opts...)
+	}()
+
+	return evCh, errCh
+}
+
+// stream holds states of a subscription across reconnections.
+type stream struct {
+	f           func(*Event) error
+	lastEventID string
+	retry       time.Duration
+	received    bool
+	closed      bool
+}
+
+func (s *stream) options(opts []hx.Option) []hx.Option {
+	newOpts := make([]hx.Option, 0, len(opts)+6)
+	newOpts = append(newOpts,
+		hx.Header("Accept", "text/event-stream"),
+		hx.Header("Cache-Control", "no-cache"),
+	)
+	if s.lastEventID != "" {
+		newOpts = append(newOpts, hx.Header("Last-Event-ID", s.lastEventID))
+	}
+	newOpts = append(newOpts, opts...)
+	newOpts = append(newOpts,
+		hx.WhenStatus(s.handleNoContent, http.StatusNoContent),
+		hx.WhenSuccess(s.handle),
+		hx.WhenFailure(hx.AsError()),
+	)
+	return newOpts
+}
+
+func (s *stream) handleNoContent(r *http.Response, err error) (*http.Response, error) {
+	s.closed = true
+	return r, err
+}
+
+func (s *stream) handle(r *http.Response, err error) (*http.Response, error) {
+	if r == nil || err != nil || s.closed {
+		return r, err
+	}
+	defer r.Body.Close()
+
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "text/event-stream" {
+		return nil, &hx.ResponseError{Response: r, Err: ErrUnexpectedContentType}
+	}
+
+	p := newParser(r.Body, s.lastEventID)
+	for {
+		ev, err := p.Next()
+		// id fields in blocks without data also update the last event ID
+		s.lastEventID = p.lastEventID
+		if p.retry > 0 {
+			s.retry = p.retry
+		}
+		if err == io.EOF {
+			return r, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.received = true
+		err = s.f(ev)
+		if err != nil {
+			return nil, &callbackError{err: err}
+		}
+	}
+}
+
+// callbackError distinguishes errors returned from callbacks from errors on reading streams.
+type callbackError struct {
+	err error
+}
+
+func (e *callbackError) Error() string { return e.err.Error() }
@@ -0,0 +1,164 @@
+package sse_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/hx"
+	"github.com/izumin5210/hx/plugins/sse"
+)
+
+func TestSubscribe(t *testing.T) {
+	var lastEventID atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/events":
+			if r.Header.Get("Accept") != "text/event-stream" {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			switch r.Header.Get("Last-Event-ID") {
+			case "":
+				fmt.Fprint(w, "\uFEFF: comment\nretry: 10\n\nid: 1\ndata: first\ndata: line\n\n")
+				fmt.Fprint(w, "id: 2\revent: update\rdata:second\r\r")
+				fmt.Fprint(w, "id: 3\ndata: incomplete")
+			case "2":
+				fmt.Fprint(w, "id: 3\r\ndata: third\r\n\r\n")
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+		case r.Method == http.MethodGet && r.URL.Path == "/ids":
+			w.Header().Set("Content-Type", "text/event-stream")
+			switch r.Header.Get("Last-Event-ID") {
+			case "":
+				fmt.Fprint(w, "retry: 10\n\nid: 1\ndata: first\n\nid: 2\n\n")
+			default:
+				lastEventID.Store(r.Header.Get("Last-Event-ID"))
+				w.WriteHeader(http.StatusNoContent)
+			}
+		case r.Method == http.MethodGet && r.URL.Path == "/text":
+			w.Write([]byte("data: text\n\n"))
+		case r.Method == http.MethodGet && r.URL.Path == "/endless":
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; ; i++ {
+				_, err := fmt.Fprintf(w, "id: %d\ndata: %d\n\n", i, i)
+				if err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("reconnect", func(t *testing.T) {
+		var (
+			got  []sse.Event
+			reqs int32
+		)
+		err := sse.Subscribe(context.Background(), ts.URL+"/events", func(ev *sse.Event) error {
+			got = append(got, *ev)
+			return nil
+		},
+			hx.InterceptFunc(func(c *http.Client, r *http.Request, next hx.RequestFunc) (*http.Response, error) {
+				atomic.AddInt32(&reqs, 1)
+				return next(c, r)
+			}),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		want := []sse.Event{
+			{ID: "1", Event: "message", Data: "first\nline"},
+			{ID: "2", Event: "update", Data: "second"},
+			{ID: "3", Event: "message", Data: "third"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+		if got, want := atomic.LoadInt32(&reqs), int32(3); got != want {
+			t.Errorf("sent %d requests, want %d", got, want)
+		}
+	})
+
+	t.Run("id without data", func(t *testing.T) {
+		var got []sse.Event
+		err := sse.Subscribe(context.Background(), ts.URL+"/ids", func(ev *sse.Event) error {
+			got = append(got, *ev)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		want := []sse.Event{
+			{ID: "1", Event: "message", Data: "first"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+		if got, want := lastEventID.Load(), "2"; got != want {
+			t.Errorf("reconnected with Last-Event-ID %q, want %q", got, want)
+		}
+	})
+
+	t.Run("callback error", func(t *testing.T) {
+		errCallback := errors.New("callback error")
+		err := sse.Subscribe(context.Background(), ts.URL+"/events", func(ev *sse.Event) error {
+			return errCallback
+		})
+		if got, want := err, errCallback; got != want {
+			t.Errorf("returned %v, want %v", got, want)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		err := sse.Subscribe(context.Background(), ts.URL+"/notfound", func(ev *sse.Event) error {
+			return nil
+		})
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("returned %v, want *hx.ResponseError", err)
+		}
+	})
+
+	t.Run("unexpected content type", func(t *testing.T) {
+		err := sse.Subscribe(context.Background(), ts.URL+"/text", func(ev *sse.Event) error {
+			return nil
+		})
+		if !errors.Is(err, sse.ErrUnexpectedContentType) {
+			t.Errorf("returned %v, want %v", err, sse.ErrUnexpectedContentType)
+		}
+	})
+
+	t.Run("channel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		evCh, errCh := sse.Events(ctx, ts.URL+"/endless")
+		for i := 0; i < 3; i++ {
+			ev := <-evCh
+			if got, want := ev.Data, fmt.Sprint(i); got != want {
+				t.Errorf("received %q, want %q", got, want)
+			}
+		}
+		cancel()
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("returned %v, want %v", err, context.Canceled)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package hx
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// Problem represents problem details for HTTP APIs (RFC 9457, formerly RFC 7807).
+// AsError sets Problem to ResponseError.Err automatically if the response has application/problem+json content type.
+//  err := hx.Get(ctx, "https://api.example.com/contents/1",
+//  	hx.WhenSuccess(hx.AsJSON(&cont)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+//  var problem *hx.Problem
+//  if errors.As(err, &problem) {
+//  	log.Printf("%s: %s", problem.Title, problem.Detail)
+//  }
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Extensions holds extension members.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+func (p *Problem) Error() string {
+	msg := p.Title
+	if msg == "" {
+		msg = http.StatusText(p.Status)
+	}
+	if msg == "" {
+		msg = "problem"
+	}
+	if p.Detail != "" {
+		msg += ": " + p.Detail
+	}
+	return msg
+}
+
+// problemMembers is used to avoid recursive calls of MarshalJSON and UnmarshalJSON.
+type problemMembers Problem
+
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var members problemMembers
+	err := json.Unmarshal(data, &members)
+	if err != nil {
+		return err
+	}
+	var exts map[string]interface{}
+	err = json.Unmarshal(data, &exts)
+	if err != nil {
+		return err
+	}
+	for _, k := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(exts, k)
+	}
+	if len(exts) > 0 {
+		members.Extensions = exts
+	}
+	*p = Problem(members)
+	return nil
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(problemMembers(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+// AsProblem is ResponseHandler that decodes the response body as Problem regardless of its content type,
+// and returns it wrapped with ResponseError.
+//  err := hx.Get(ctx, "https://api.example.com/contents/1",
+//  	hx.WhenSuccess(hx.AsJSON(&cont)),
+//  	hx.WhenFailure(hx.AsProblem()),
+//  )
+func AsProblem() ResponseHandler {
+	return func(r *http.Response, err error) (*http.Response, error) {
+		if r == nil || err != nil {
+			return r, err
+		}
+		p := new(Problem)
+		_, err = DefaultJSONConfig.AsJSONError(p)(r, nil)
+		if p.Status == 0 {
+			p.Status = r.StatusCode
+		}
+		return nil, err
+	}
+}
+
+// decodeProblem decodes a body captured by AsError if the response has application/problem+json content type.
+func decodeProblem(r *http.Response, body []byte) (*Problem, bool) {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt != "application/problem+json" || len(body) == 0 {
+		return nil, false
+	}
+	p := new(Problem)
+	if err := DefaultJSONConfig.decode(bytes.NewReader(body), p); err != nil {
+		return nil, false
+	}
+	if p.Status == 0 {
+		p.Status = r.StatusCode
+	}
+	return p, true
+}
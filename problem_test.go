@@ -0,0 +1,96 @@
+package hx_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/izumin5210/hx"
+)
+
+func TestProblem(t *testing.T) {
+	const body = `{"type":"https://example.com/probs/out-of-credit",` +
+		`"title":"You do not have enough credit.",` +
+		`"detail":"Your current balance is 30, but that costs 50.",` +
+		`"instance":"/account/12345/msgs/abc","balance":30}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/problem":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(body))
+		case r.Method == http.MethodGet && r.URL.Path == "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	want := &hx.Problem{
+		Type:       "https://example.com/probs/out-of-credit",
+		Title:      "You do not have enough credit.",
+		Status:     http.StatusForbidden,
+		Detail:     "Your current balance is 30, but that costs 50.",
+		Instance:   "/account/12345/msgs/abc",
+		Extensions: map[string]interface{}{"balance": 30.0},
+	}
+
+	cases := []struct {
+		test    string
+		path    string
+		handler hx.ResponseHandler
+		want    *hx.Problem
+	}{
+		{test: "AsError with problem+json", path: "/problem", handler: hx.AsError(), want: want},
+		{test: "AsError with json", path: "/json", handler: hx.AsError()},
+		{test: "AsProblem", path: "/json", handler: hx.AsProblem(), want: want},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			err := hx.Get(context.Background(), ts.URL+tc.path, hx.WhenFailure(tc.handler))
+
+			var respErr *hx.ResponseError
+			if !errors.As(err, &respErr) {
+				t.Errorf("returned %v, want *hx.ResponseError", err)
+			}
+
+			var problem *hx.Problem
+			if got, want := errors.As(err, &problem), tc.want != nil; got != want {
+				t.Fatalf("errors.As(err, *hx.Problem) returned %t, want %t", got, want)
+			}
+			if tc.want == nil {
+				return
+			}
+			if !reflect.DeepEqual(problem, tc.want) {
+				t.Errorf("returned %#v, want %#v", problem, tc.want)
+			}
+			if got, want := problem.Error(), "You do not have enough credit.: Your current balance is 30, but that costs 50."; got != want {
+				t.Errorf("Error() returned %q, want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("MarshalJSON", func(t *testing.T) {
+		data, err := json.Marshal(want)
+		if err != nil {
+			t.Fatalf("returned %v, want nil", err)
+		}
+		var got hx.Problem
+		err = json.Unmarshal(data, &got)
+		if err != nil {
+			t.Fatalf("returned %v, want nil", err)
+		}
+		if !reflect.DeepEqual(&got, want) {
+			t.Errorf("returned %#v, want %#v", &got, want)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package hx
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryStruct sets url query parameters from fields of a given struct.
+// Fields are encoded with the `url` struct tag, and merged into the query parameters set by Query and the URL.
+//  type SearchQuery struct {
+//  	Keyword string     `url:"q"`
+//  	Tags    []string   `url:"tags,comma,omitempty"`
+//  	Since   *time.Time `url:"since,omitempty" layout:"2006-01-02"`
+//  	Page    int        `url:"page,omitempty"`
+//  }
+//
+//  err := hx.Get(ctx, "https://api.example.com/search",
+//  	hx.QueryStruct(&SearchQuery{Keyword: "hx", Tags: []string{"go", "http"}}),
+//  )
+//
+// The tag value is a name of the parameter optionally followed by comma-separated options:
+//  omitempty  omits the parameter if the value is empty, or nil for pointers
+//  comma      encodes slices as a comma-separated value (tags=go,http)
+//  brackets   encodes slices with brackets (tags[]=go&tags[]=http)
+// Slices are encoded as repeated parameters (tags=go&tags=http) in default.
+// Fields with the tag "-" are ignored, and embedded structs without tags are flattened.
+// time.Time is formatted with the layout in the `layout` tag, or time.RFC3339 in default.
+// Nil pointers are omitted, and encoding.TextMarshaler and fmt.Stringer are encoded with themselves.
+func QueryStruct(v interface{}) Option {
+	return OptionFunc(func(c *Config) error {
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return nil
+			}
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Struct {
+			return fmt.Errorf("hx: QueryStruct requires a struct, but got %s", rv.Type())
+		}
+		return encodeQueryStruct(c.QueryParams, rv)
+	})
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+type queryField struct {
+	name      string
+	omitEmpty bool
+	comma     bool
+	brackets  bool
+	layout    string
+}
+
+func parseQueryField(f reflect.StructField) (qf queryField, ok bool) {
+	tag, hasTag := f.Tag.Lookup("url")
+	if tag == "-" {
+		return qf, false
+	}
+	opts := strings.Split(tag, ",")
+	qf.name = opts[0]
+	if !hasTag || qf.name == "" {
+		qf.name = f.Name
+	}
+	for _, o := range opts[1:] {
+		switch o {
+		case "omitempty":
+			qf.omitEmpty = true
+		case "comma":
+			qf.comma = true
+		case "brackets":
+			qf.brackets = true
+		}
+	}
+	qf.layout = f.Tag.Get("layout")
+	if qf.layout == "" {
+		qf.layout = time.RFC3339
+	}
+	return qf, true
+}
+
+func encodeQueryStruct(q url.Values, rv reflect.Value) error {
+	rt := rv.Type()
+
+	for i, n := 0, rt.NumField(); i < n; i++ {
+		f := rt.Field(i)
+		fv := rv.Field(i)
+
+		if _, hasTag := f.Tag.Lookup("url"); f.Anonymous && !hasTag {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !isQueryScalar(ft) {
+				if fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				err := encodeQueryStruct(q, fv)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		qf, ok := parseQueryField(f)
+		if !ok {
+			continue
+		}
+
+		// omitempty omits only nil for pointers, so pointers can be used to send zero values explicitly
+		omitEmpty := qf.omitEmpty && fv.Kind() != reflect.Ptr && fv.Kind() != reflect.Interface
+
+		for fv.Kind() == reflect.Ptr && !isQueryScalar(fv.Type()) {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if (fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface) && fv.IsNil() {
+			continue
+		}
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		if (fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array) && !isQueryScalar(fv.Type()) {
+			values := make([]string, fv.Len())
+			for j := range values {
+				s, err := formatQueryValue(fv.Index(j), qf)
+				if err != nil {
+					return fmt.Errorf("hx: failed to encode query %q: %w", qf.name, err)
+				}
+				values[j] = s
+			}
+			if omitEmpty && len(values) == 0 {
+				continue
+			}
+			switch {
+			case qf.comma:
+				q.Add(qf.name, strings.Join(values, ","))
+			case qf.brackets:
+				for _, s := range values {
+					q.Add(qf.name+"[]", s)
+				}
+			default:
+				for _, s := range values {
+					q.Add(qf.name, s)
+				}
+			}
+			continue
+		}
+
+		s, err := formatQueryValue(fv, qf)
+		if err != nil {
+			return fmt.Errorf("hx: failed to encode query %q: %w", qf.name, err)
+		}
+		q.Add(qf.name, s)
+	}
+
+	return nil
+}
+
+func isQueryScalar(t reflect.Type) bool {
+	return t == timeType || t.Implements(textMarshalerType) || t.Implements(stringerType) ||
+		(t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+}
+
+func formatQueryValue(rv reflect.Value, qf queryField) (string, error) {
+	if rv.CanInterface() {
+		switch v := rv.Interface().(type) {
+		case time.Time:
+			return v.Format(qf.layout), nil
+		case *time.Time:
+			return v.Format(qf.layout), nil
+		case encoding.TextMarshaler:
+			data, err := v.MarshalText()
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		case fmt.Stringer:
+			return v.String(), nil
+		}
+	}
+
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return "", nil
+		}
+		rv = rv.Elem()
+		if isQueryScalar(rv.Type()) {
+			return formatQueryValue(rv, qf)
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return string(rv.Bytes()), nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported type %s", rv.Type())
+}
@@ -0,0 +1,139 @@
+package hx_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/hx"
+)
+
+type fakeQueryPage struct {
+	Page    int `url:"page,omitempty"`
+	PerPage int `url:"per_page,omitempty"`
+}
+
+type fakeQueryFilter struct {
+	Keyword  string            `url:"q"`
+	Tags     []string          `url:"tags,omitempty"`
+	Labels   []string          `url:"labels,comma,omitempty"`
+	IDs      []int             `url:"ids,brackets,omitempty"`
+	Since    time.Time         `url:"since,omitempty" layout:"2006-01-02"`
+	Until    *time.Time        `url:"until,omitempty"`
+	Draft    *bool             `url:"draft"`
+	Archived *bool             `url:"archived,omitempty"`
+	PerPage  *int              `url:"per_page,omitempty"`
+	Score    float64           `url:"score,omitempty"`
+	Sort     fakeTextMarshaler `url:"sort,omitempty"`
+	Ignored  string            `url:"-"`
+	NoTag    string
+	internal string
+	fakeQueryPage
+}
+
+func TestQueryStruct(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/search":
+			w.Write([]byte(r.URL.RawQuery))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	draft := false
+	perPage := 0
+	until := time.Date(2019, 12, 31, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		test string
+		url  string
+		opts []hx.Option
+		want string
+	}{
+		{
+			test: "empty",
+			url:  "/search",
+			opts: []hx.Option{hx.QueryStruct(&fakeQueryFilter{})},
+			want: "NoTag=&q=",
+		},
+		{
+			test: "full",
+			url:  "/search",
+			opts: []hx.Option{hx.QueryStruct(&fakeQueryFilter{
+				Keyword:       "hx",
+				Tags:          []string{"go", "http"},
+				Labels:        []string{"a", "b"},
+				IDs:           []int{1, 2},
+				Since:         time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+				Until:         &until,
+				Draft:         &draft,
+				Archived:      &draft,
+				PerPage:       &perPage,
+				Score:         1.5,
+				Sort:          fakeTextMarshaler("desc"),
+				Ignored:       "ignored",
+				NoTag:         "notag",
+				internal:      "internal",
+				fakeQueryPage: fakeQueryPage{Page: 2},
+			})},
+			want: "NoTag=notag&archived=false&draft=false&ids%5B%5D=1&ids%5B%5D=2&labels=a%2Cb&page=2&per_page=0&q=hx&score=1.5" +
+				"&since=2019-12-01&sort=desc&tags=go&tags=http&until=2019-12-31T12%3A00%3A00Z",
+		},
+		{
+			test: "merge",
+			url:  "/search?q=foo",
+			opts: []hx.Option{
+				hx.Query("page", "1"),
+				hx.QueryStruct(struct {
+					Keyword string `url:"q"`
+				}{Keyword: "bar"}),
+			},
+			want: "page=1&q=foo&q=bar",
+		},
+		{
+			test: "nil",
+			url:  "/search",
+			opts: []hx.Option{hx.QueryStruct((*fakeQueryFilter)(nil))},
+			want: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := append(tc.opts, hx.WhenSuccess(hx.AsBytesBuffer(&buf)), hx.WhenFailure(hx.AsError()))
+			err := hx.Get(context.Background(), ts.URL+tc.url, opts...)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if got, want := buf.String(), tc.want; got != want {
+				t.Errorf("sent query %q, want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("not a struct", func(t *testing.T) {
+		err := hx.Get(context.Background(), ts.URL+"/search",
+			hx.QueryStruct("q=hx"),
+		)
+		if err == nil {
+			t.Error("returned nil, want an error")
+		}
+	})
+
+	t.Run("unsupported field", func(t *testing.T) {
+		err := hx.Get(context.Background(), ts.URL+"/search",
+			hx.QueryStruct(struct {
+				Filter map[string]string `url:"filter"`
+			}{Filter: map[string]string{}}),
+		)
+		if err == nil {
+			t.Error("returned nil, want an error")
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package hx
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Response wraps *http.Response returned by Client.Do.
+// Its body has been already read, so Body can be read after the connection is released.
+type Response struct {
+	*http.Response
+
+	// StartedAt is the time when the request was started.
+	StartedAt time.Time
+	// Duration is the time elapsed from StartedAt until the response body was read completely.
+	Duration time.Duration
+
+	body []byte
+}
+
+func newResponse(r *http.Response, startedAt time.Time) (*Response, error) {
+	resp := &Response{Response: r, StartedAt: startedAt}
+
+	var err error
+	if r.Body != nil {
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(r.Body)
+		cerr := r.Body.Close()
+		if err == nil {
+			err = cerr
+		}
+		resp.body = buf.Bytes()
+		r.Body = ioutil.NopCloser(bytes.NewReader(resp.body))
+	}
+
+	resp.Duration = time.Since(startedAt)
+
+	return resp, err
+}
+
+// Bytes returns the part of the response body that was not consumed by response handlers.
+func (r *Response) Bytes() []byte { return r.body }
+
+// trackResponseBody makes the response body readable after it is closed by response handlers.
+// A closed body behaves as if it had reached EOF.
+func trackResponseBody(r *http.Response, err error) (*http.Response, error) {
+	if r == nil || r.Body == nil || r.Body == http.NoBody {
+		return r, err
+	}
+	r.Body = &trackedBody{ReadCloser: r.Body}
+	return r, err
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	closed bool
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	if b.closed {
+		return 0, io.EOF
+	}
+	return b.ReadCloser.Read(p)
+}
+
+func (b *trackedBody) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return b.ReadCloser.Close()
+}
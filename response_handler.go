@@ -2,7 +2,10 @@ package hx
 
 import (
 	"bytes"
-	"fmt"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
 	"net/http"
 
 	"github.com/izumin5210/hx/hxutil"
@@ -17,33 +20,47 @@ func HandleResponse(f func(*http.Response, error) (*http.Response, error)) Optio
 	})
 }
 
-type ResponseError struct {
-	Response *http.Response
-	Err      error
-}
-
-func (e *ResponseError) Error() string {
-	msg := fmt.Sprintf("the server responeded with status %d", e.Response.StatusCode)
-	if e.Err != nil {
-		msg = fmt.Sprintf("%s: %s", msg, e.Err.Error())
-	}
-	return msg
-}
+func AsJSON(dst interface{}) ResponseHandler { return DefaultJSONConfig.AsJSON(dst) }
 
-func (e *ResponseError) Unwrap() error {
-	if e.Err != nil {
-		return e.Err
-	}
-	return e
+// AsJSONStream is ResponseHandler that calls a given callback for each record in a JSON array or a stream of JSON values.
+// The callback should decode exactly one record with the decoder.
+// The callback can return StopStream to stop reading the stream without errors.
+// Since the callback decodes records with json.Decoder directly, JSONConfig is not used.
+// Use AsNDJSONWith to decode records with JSONConfig.DecodeFunc.
+//  err := hx.Get(ctx, "https://api.example.com/exports",
+//  	hx.WhenSuccess(hx.AsJSONStream(func(dec *json.Decoder) error {
+//  		var rec Record
+//  		if err := dec.Decode(&rec); err != nil {
+//  			return err
+//  		}
+//  		// handle the record...
+//  		return nil
+//  	})),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func AsJSONStream(f func(*json.Decoder) error) ResponseHandler {
+	return handleStream(func(ctx context.Context, r io.Reader) error {
+		return readJSONStream(ctx, r, f)
+	})
 }
 
-func AsJSON(dst interface{}) ResponseHandler { return DefaultJSONConfig.AsJSON(dst) }
+// AsXML is ResponseHandler that decodes the response body as XML.
+// Responses in non-UTF-8 charsets, given by Content-Type or XML declarations, need XMLConfig.CharsetReader.
+//  cfg := &hx.XMLConfig{CharsetReader: charset.NewReaderLabel} // golang.org/x/net/html/charset
+//  err := hx.Get(ctx, "https://api.example.com/contents/1",
+//  	hx.WhenSuccess(cfg.AsXML(&cont)),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func AsXML(dst interface{}) ResponseHandler { return DefaultXMLConfig.AsXML(dst) }
 
 func AsBytesBuffer(dst *bytes.Buffer) ResponseHandler {
 	return func(r *http.Response, err error) (*http.Response, error) {
 		if r == nil || err != nil {
 			return r, err
 		}
+		if !hasBody(r) {
+			return r, nil
+		}
 		defer r.Body.Close()
 		_, err = dst.ReadFrom(r.Body)
 		if err != nil {
@@ -53,16 +70,28 @@ func AsBytesBuffer(dst *bytes.Buffer) ResponseHandler {
 	}
 }
 
+// maxErrorBodyBytes is the max size of response bodies kept by AsError.
+const maxErrorBodyBytes = 64 << 10
+
+// AsError is ResponseHandler that returns ResponseError.
+// The first 64 KiB of the response body is kept in the response and ResponseError.Body for error reporting.
+// If the response has application/problem+json content type, ResponseError.Err is set to Problem.
 func AsError() ResponseHandler {
 	return func(r *http.Response, err error) (*http.Response, error) {
 		if r == nil || err != nil {
 			return r, err
 		}
-		err = hxutil.DrainResponseBody(r)
+		err = hxutil.DrainResponseBodyN(r, maxErrorBodyBytes)
 		if err != nil {
 			return nil, &ResponseError{Response: r, Err: err}
 		}
-		return r, &ResponseError{Response: r}
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		respErr := &ResponseError{Response: r, Body: body}
+		if p, ok := decodeProblem(r, body); ok {
+			respErr.Err = p
+		}
+		return r, respErr
 	}
 }
 
@@ -90,6 +119,27 @@ func AsError() ResponseHandler {
 //  }
 func AsJSONError(dst error) ResponseHandler { return DefaultJSONConfig.AsJSONError(dst) }
 
+// AsXMLError is ResponseHandler that will populate an error with the XML returned within the response body.
+// And it will wrap the error with ResponseError and return it.
+func AsXMLError(dst error) ResponseHandler { return DefaultXMLConfig.AsXMLError(dst) }
+
+// hasBody reports whether a given response can have a body.
+// Responses to HEAD requests, and responses with 1xx, 204 or 304 status never have a body.
+// Other responses with empty bodies are still decoded, so handlers report decoding errors for them.
+func hasBody(r *http.Response) bool {
+	if r.Body == nil {
+		return false
+	}
+	if r.Request != nil && r.Request.Method == http.MethodHead {
+		return false
+	}
+	switch {
+	case r.StatusCode/100 == 1, r.StatusCode == http.StatusNoContent, r.StatusCode == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
 func checkStatus(f func(int) bool) func(*http.Response, error) bool {
 	return func(r *http.Response, err error) bool {
 		return err == nil && r != nil && f(r.StatusCode)
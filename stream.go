@@ -0,0 +1,164 @@
+package hx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// StopStream can be returned from callbacks of stream handlers to stop reading the stream without errors.
+var StopStream = errors.New("hx: stop stream")
+
+// StreamError reports which record in a stream caused an error.
+// It is wrapped with ResponseError by stream handlers.
+type StreamError struct {
+	// Index is the 0-based index of the record.
+	Index int
+	Err   error
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("failed to handle the record #%d in the stream: %s", e.Index, e.Err.Error())
+}
+
+func (e *StreamError) Unwrap() error { return e.Err }
+
+// AsNDJSON is ResponseHandler that decodes newline-delimited JSON records, and calls a given callback with each record as it arrives.
+// The record is decoded with DefaultJSONConfig.
+//  err := hx.Get(ctx, "https://api.example.com/exports",
+//  	hx.WhenSuccess(hx.AsNDJSON(func(rec *Record) error {
+//  		// handle the record...
+//  		return nil
+//  	})),
+//  	hx.WhenFailure(hx.AsError()),
+//  )
+func AsNDJSON[T any](f func(T) error) ResponseHandler { return AsNDJSONWith(DefaultJSONConfig, f) }
+
+// AsNDJSONWith is the same as AsNDJSON, but it decodes records with a given JSONConfig.
+func AsNDJSONWith[T any](c *JSONConfig, f func(T) error) ResponseHandler {
+	return handleStream(func(ctx context.Context, r io.Reader) error {
+		br := bufio.NewReader(r)
+
+		for i := 0; ; {
+			line, err := br.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return &StreamError{Index: i, Err: err}
+			}
+			eof := err == io.EOF
+
+			line = bytes.TrimSpace(line)
+			if len(line) > 0 {
+				if err := ctx.Err(); err != nil {
+					return &StreamError{Index: i, Err: err}
+				}
+
+				var v T
+				err = c.decode(bytes.NewReader(line), &v)
+				if err != nil {
+					return &StreamError{Index: i, Err: err}
+				}
+				err = f(v)
+				if errors.Is(err, StopStream) {
+					return nil
+				}
+				if err != nil {
+					return &StreamError{Index: i, Err: err}
+				}
+				i++
+			}
+
+			if eof {
+				return nil
+			}
+		}
+	})
+}
+
+// readJSONStream calls a given callback for each record in a JSON array or concatenated JSON values.
+func readJSONStream(ctx context.Context, r io.Reader, f func(*json.Decoder) error) error {
+	br := bufio.NewReader(r)
+
+	isArray, err := peekJSONArray(br)
+	if err != nil {
+		return &StreamError{Index: 0, Err: err}
+	}
+
+	dec := json.NewDecoder(br)
+	if isArray {
+		_, err = dec.Token()
+		if err != nil {
+			return &StreamError{Index: 0, Err: err}
+		}
+	}
+
+	i := 0
+	for ; dec.More(); i++ {
+		if err := ctx.Err(); err != nil {
+			return &StreamError{Index: i, Err: err}
+		}
+		err := f(dec)
+		if errors.Is(err, StopStream) {
+			return nil
+		}
+		if err != nil {
+			return &StreamError{Index: i, Err: err}
+		}
+	}
+
+	// make sure that the stream has been terminated correctly.
+	tok, err := dec.Token()
+	switch {
+	case isArray && err == nil && tok == json.Delim(']'):
+		return nil
+	case !isArray && err == io.EOF:
+		return nil
+	case err == nil || err == io.EOF:
+		err = io.ErrUnexpectedEOF
+	}
+	return &StreamError{Index: i, Err: err}
+}
+
+func peekJSONArray(br *bufio.Reader) (bool, error) {
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c == '[', br.UnreadByte()
+	}
+}
+
+func handleStream(f func(context.Context, io.Reader) error) ResponseHandler {
+	return func(r *http.Response, err error) (*http.Response, error) {
+		if r == nil || err != nil {
+			return r, err
+		}
+		if !hasBody(r) {
+			return r, nil
+		}
+
+		ctx := context.Background()
+		if r.Request != nil {
+			ctx = r.Request.Context()
+		}
+
+		defer r.Body.Close()
+		err = f(ctx, r.Body)
+		if err != nil {
+			return nil, &ResponseError{Response: r, Err: err}
+		}
+		return r, nil
+	}
+}
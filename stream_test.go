@@ -0,0 +1,268 @@
+package hx_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/izumin5210/hx"
+)
+
+func TestAsNDJSON(t *testing.T) {
+	type Record struct {
+		ID int `json:"id"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/records":
+			w.Write([]byte("{\"id\":1}\n\n{\"id\":2}\n{\"id\":3}"))
+		case r.Method == http.MethodGet && r.URL.Path == "/truncated":
+			w.Write([]byte("{\"id\":1}\n{\"id\":2}\n{\"id\""))
+		case r.Method == http.MethodGet && r.URL.Path == "/endless":
+			for i := 0; ; i++ {
+				_, err := fmt.Fprintf(w, "{\"id\":%d}\n", i)
+				if err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+					return
+				default:
+				}
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("simple", func(t *testing.T) {
+		var got []int
+		err := hx.Get(context.Background(), ts.URL+"/records",
+			hx.WhenSuccess(hx.AsNDJSON(func(r Record) error {
+				got = append(got, r.ID)
+				return nil
+			})),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		var got []int
+		err := hx.Get(context.Background(), ts.URL+"/truncated",
+			hx.WhenSuccess(hx.AsNDJSON(func(r *Record) error {
+				got = append(got, r.ID)
+				return nil
+			})),
+			hx.WhenFailure(hx.AsError()),
+		)
+		var streamErr *hx.StreamError
+		if !errors.As(err, &streamErr) {
+			t.Errorf("returned %v, want *hx.StreamError", err)
+		} else if got, want := streamErr.Index, 2; got != want {
+			t.Errorf("returned index %d, want %d", got, want)
+		}
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("returned %v, want *hx.ResponseError", err)
+		}
+		if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		var got []int
+		err := hx.Get(context.Background(), ts.URL+"/endless",
+			hx.WhenSuccess(hx.AsNDJSON(func(r Record) error {
+				got = append(got, r.ID)
+				if len(got) == 3 {
+					return hx.StopStream
+				}
+				return nil
+			})),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if want := []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	})
+
+	t.Run("callback error", func(t *testing.T) {
+		errCallback := errors.New("callback error")
+		err := hx.Get(context.Background(), ts.URL+"/records",
+			hx.WhenSuccess(hx.AsNDJSON(func(r Record) error {
+				if r.ID == 2 {
+					return errCallback
+				}
+				return nil
+			})),
+			hx.WhenFailure(hx.AsError()),
+		)
+		var streamErr *hx.StreamError
+		if !errors.Is(err, errCallback) {
+			t.Errorf("returned %v, want %v", err, errCallback)
+		} else if !errors.As(err, &streamErr) {
+			t.Errorf("returned %v, want *hx.StreamError", err)
+		} else if got, want := streamErr.Index, 1; got != want {
+			t.Errorf("returned index %d, want %d", got, want)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var cnt int
+		err := hx.Get(ctx, ts.URL+"/endless",
+			hx.WhenSuccess(hx.AsNDJSON(func(r Record) error {
+				cnt++
+				if cnt == 3 {
+					cancel()
+				}
+				return nil
+			})),
+			hx.WhenFailure(hx.AsError()),
+		)
+		var streamErr *hx.StreamError
+		if !errors.As(err, &streamErr) {
+			t.Errorf("returned %v, want *hx.StreamError", err)
+		}
+		if got, want := cnt, 3; got != want {
+			t.Errorf("received %d records, want %d", got, want)
+		}
+	})
+
+	t.Run("custom decoder", func(t *testing.T) {
+		cfg := &hx.JSONConfig{
+			DecodeFunc: func(r io.Reader, v interface{}) error {
+				var rec Record
+				err := json.NewDecoder(r).Decode(&rec)
+				if err != nil {
+					return err
+				}
+				rec.ID *= 10
+				*v.(*Record) = rec
+				return nil
+			},
+		}
+		var got []int
+		err := hx.Get(context.Background(), ts.URL+"/records",
+			hx.WhenSuccess(hx.AsNDJSONWith(cfg, func(r Record) error {
+				got = append(got, r.ID)
+				return nil
+			})),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if want := []int{10, 20, 30}; !reflect.DeepEqual(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	})
+}
+
+func TestAsJSONStream(t *testing.T) {
+	type Record struct {
+		ID int `json:"id"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/array":
+			w.Write([]byte(` [{"id":1}, {"id":2}, {"id":3}] `))
+		case r.Method == http.MethodGet && r.URL.Path == "/values":
+			w.Write([]byte(`{"id":1} {"id":2}` + "\n" + `{"id":3}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/truncated_array":
+			w.Write([]byte(`[{"id":1}, {"id":2}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/truncated_values":
+			w.Write([]byte(`{"id":1} {"id":2} {"id":`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	decode := func(got *[]int) func(*json.Decoder) error {
+		return func(dec *json.Decoder) error {
+			var r Record
+			err := dec.Decode(&r)
+			if err != nil {
+				return err
+			}
+			*got = append(*got, r.ID)
+			return nil
+		}
+	}
+
+	for _, path := range []string{"/array", "/values"} {
+		t.Run(path, func(t *testing.T) {
+			var got []int
+			err := hx.Get(context.Background(), ts.URL+path,
+				hx.WhenSuccess(hx.AsJSONStream(decode(&got))),
+				hx.WhenFailure(hx.AsError()),
+			)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+				t.Errorf("received %v, want %v", got, want)
+			}
+		})
+	}
+
+	for _, path := range []string{"/truncated_array", "/truncated_values"} {
+		t.Run(path, func(t *testing.T) {
+			var got []int
+			err := hx.Get(context.Background(), ts.URL+path,
+				hx.WhenSuccess(hx.AsJSONStream(decode(&got))),
+				hx.WhenFailure(hx.AsError()),
+			)
+			var streamErr *hx.StreamError
+			if !errors.As(err, &streamErr) {
+				t.Errorf("returned %v, want *hx.StreamError", err)
+			} else if got, want := streamErr.Index, 2; got != want {
+				t.Errorf("returned index %d, want %d", got, want)
+			}
+			if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+				t.Errorf("received %v, want %v", got, want)
+			}
+		})
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		var got []int
+		err := hx.Get(context.Background(), ts.URL+"/array",
+			hx.WhenSuccess(hx.AsJSONStream(func(dec *json.Decoder) error {
+				err := decode(&got)(dec)
+				if err == nil && len(got) == 2 {
+					return hx.StopStream
+				}
+				return err
+			})),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package hx
+
+import (
+	"context"
+	"net/http"
+)
+
+// GetJSON sends a GET request and decodes the successful response body as T.
+// A response with non-2xx status is returned as *ResponseError.
+//  post, err := hx.GetJSON[Post](ctx, "https://api.example.com/posts/1")
+func GetJSON[T any](ctx context.Context, url string, opts ...Option) (T, error) {
+	return GetJSONWith[T](ctx, NewClient(), url, opts...)
+}
+
+// GetJSONWith is the same as GetJSON, but it sends a request with a given client.
+func GetJSONWith[T any](ctx context.Context, c *Client, url string, opts ...Option) (T, error) {
+	return callJSON[T](ctx, c, http.MethodGet, url, opts...)
+}
+
+// Call sends a request with a given data as JSON body and decodes the successful response body as Resp.
+// A response with non-2xx status is returned as *ResponseError.
+// Response handlers given as options take precedence over the default ones.
+//  post, err := hx.Call[*CreatePostRequest, Post](ctx, http.MethodPost, "https://api.example.com/posts",
+//  	&CreatePostRequest{Body: "Hello!"},
+//  	hx.WhenStatus(hx.AsJSONError(&InvalidArgument{}), http.StatusBadRequest),
+//  )
+func Call[Req, Resp any](ctx context.Context, meth string, url string, req Req, opts ...Option) (Resp, error) {
+	return CallWith[Req, Resp](ctx, NewClient(), meth, url, req, opts...)
+}
+
+// CallWith is the same as Call, but it sends a request with a given client.
+func CallWith[Req, Resp any](ctx context.Context, c *Client, meth string, url string, req Req, opts ...Option) (Resp, error) {
+	newOpts := make([]Option, 0, len(opts)+1)
+	newOpts = append(newOpts, JSON(req))
+	newOpts = append(newOpts, opts...)
+	return callJSON[Resp](ctx, c, meth, url, newOpts...)
+}
+
+func callJSON[T any](ctx context.Context, c *Client, meth string, url string, opts ...Option) (T, error) {
+	var out T
+
+	newOpts := make([]Option, 0, len(opts)+2)
+	newOpts = append(newOpts, opts...)
+	newOpts = append(newOpts,
+		WhenSuccess(AsJSON(&out)),
+		WhenFailure(AsError()),
+	)
+
+	err := c.request(ctx, meth, url, newOpts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return out, nil
+}
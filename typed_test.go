@@ -0,0 +1,147 @@
+package hx_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/izumin5210/hx"
+)
+
+func TestGetJSON(t *testing.T) {
+	type Post struct {
+		Message string `json:"message"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/echo":
+			json.NewEncoder(w).Encode(&Post{Message: r.URL.Query().Get("message")})
+		case r.Method == http.MethodGet && r.URL.Path == "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(&Post{Message: "internal error"})
+		case r.Method == http.MethodGet && r.URL.Path == "/empty":
+			w.Header().Set("Content-Length", "0")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("success", func(t *testing.T) {
+		out, err := hx.GetJSON[Post](context.Background(), ts.URL+"/echo",
+			hx.Query("message", "It Works!"),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := out.Message, "It Works!"; got != want {
+			t.Errorf("returned %q, want %q", got, want)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		out, err := hx.GetJSON[*Post](context.Background(), ts.URL+"/error")
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("returned %v, want *hx.ResponseError", err)
+		} else if got, want := respErr.Response.StatusCode, http.StatusInternalServerError; got != want {
+			t.Errorf("returned status %d, want %d", got, want)
+		}
+		if out != nil {
+			t.Errorf("returned %v, want nil", out)
+		}
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		out, err := hx.GetJSON[*Post](context.Background(), ts.URL+"/empty")
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("returned %v, want *hx.ResponseError", err)
+		}
+		if out != nil {
+			t.Errorf("returned %v, want nil", out)
+		}
+	})
+
+	t.Run("with client", func(t *testing.T) {
+		u, _ := url.Parse(ts.URL)
+		cli := hx.NewClient(hx.BaseURL(u))
+		out, err := hx.GetJSONWith[Post](context.Background(), cli, "/echo",
+			hx.Query("message", "It Works!"),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := out.Message, "It Works!"; got != want {
+			t.Errorf("returned %q, want %q", got, want)
+		}
+	})
+}
+
+func TestCall(t *testing.T) {
+	type Request struct {
+		Message string `json:"message"`
+	}
+	type Response struct {
+		Message string `json:"message"`
+		Length  int    `json:"length"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/echo":
+			var req Request
+			err := json.NewDecoder(r.Body).Decode(&req)
+			if err != nil || req.Message == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"message": "invalid argument"})
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(&Response{Message: req.Message, Length: len(req.Message)})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("success", func(t *testing.T) {
+		out, err := hx.Call[*Request, Response](context.Background(), http.MethodPost, ts.URL+"/echo",
+			&Request{Message: "Hello!"},
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := out, (Response{Message: "Hello!", Length: 6}); got != want {
+			t.Errorf("returned %v, want %v", got, want)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		_, err := hx.Call[*Request, Response](context.Background(), http.MethodPost, ts.URL+"/echo",
+			&Request{},
+		)
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("returned %v, want *hx.ResponseError", err)
+		} else if got, want := respErr.Response.StatusCode, http.StatusBadRequest; got != want {
+			t.Errorf("returned status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("custom error handler", func(t *testing.T) {
+		_, err := hx.Call[*Request, Response](context.Background(), http.MethodPost, ts.URL+"/echo",
+			&Request{},
+			hx.WhenStatus(hx.AsJSONError(&fakeError{}), http.StatusBadRequest),
+		)
+		var fakeErr *fakeError
+		if !errors.As(err, &fakeErr) {
+			t.Errorf("returned %v, want *fakeError", err)
+		} else if got, want := fakeErr.Message, "invalid argument"; got != want {
+			t.Errorf("returned message %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,286 @@
+package hx
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// URLTemplate expands an URI template (RFC 6570) with given parameters, and sets the result as URL in the same way as URL.
+//  err := cli.Get(ctx, "", hx.URLTemplate("/users/{id}/posts{?page,limit}", map[string]interface{}{
+//  	"id":    "foo/bar",
+//  	"page":  2,
+//  	"limit": nil,
+//  }))
+//  // GET /users/foo%2Fbar/posts?page=2
+func URLTemplate(tmpl string, params map[string]interface{}) Option {
+	return OptionFunc(func(c *Config) error {
+		u, err := ExpandURLTemplate(tmpl, params)
+		if err != nil {
+			return err
+		}
+		return URL(u).ApplyOption(c)
+	})
+}
+
+// ExpandURLTemplate expands an URI template (RFC 6570) with given parameters.
+// It supports all expressions up to level 4, including reserved expansion, prefix and explode modifiers.
+// Values can be strings, numbers, fmt.Stringer, slices (lists) and maps (associative arrays).
+//
+// Unlike RFC 6570, variables that are missing from params are reported as an error.
+// To omit a variable explicitly, set nil to it.
+func ExpandURLTemplate(tmpl string, params map[string]interface{}) (string, error) {
+	var b strings.Builder
+
+	for rest := tmpl; len(rest) > 0; {
+		i := strings.IndexByte(rest, '{')
+		if i < 0 {
+			b.WriteString(escapeURLTemplate(rest, true))
+			break
+		}
+		b.WriteString(escapeURLTemplate(rest[:i], true))
+		rest = rest[i+1:]
+
+		j := strings.IndexByte(rest, '}')
+		if j < 0 {
+			return "", fmt.Errorf("hx: URL template %q has an unclosed expression", tmpl)
+		}
+		err := expandURLTemplateExpr(&b, rest[:j], params)
+		if err != nil {
+			return "", fmt.Errorf("hx: URL template %q: %w", tmpl, err)
+		}
+		rest = rest[j+1:]
+	}
+
+	return b.String(), nil
+}
+
+type urlTemplateOp struct {
+	first         string
+	sep           string
+	named         bool
+	ifEmpty       string
+	allowReserved bool
+}
+
+var urlTemplateOps = map[byte]urlTemplateOp{
+	'+': {first: "", sep: ",", allowReserved: true},
+	'#': {first: "#", sep: ",", allowReserved: true},
+	'.': {first: ".", sep: "."},
+	'/': {first: "/", sep: "/"},
+	';': {first: ";", sep: ";", named: true},
+	'?': {first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", sep: "&", named: true, ifEmpty: "="},
+}
+
+func expandURLTemplateExpr(b *strings.Builder, expr string, params map[string]interface{}) error {
+	op := urlTemplateOp{sep: ","}
+	if len(expr) > 0 {
+		if o, ok := urlTemplateOps[expr[0]]; ok {
+			op = o
+			expr = expr[1:]
+		}
+	}
+	if expr == "" {
+		return fmt.Errorf("empty expression")
+	}
+
+	first := true
+
+	for _, spec := range strings.Split(expr, ",") {
+		name, explode, prefix, err := parseURLTemplateVarSpec(spec)
+		if err != nil {
+			return err
+		}
+
+		v, ok := params[name]
+		if !ok {
+			return fmt.Errorf("undefined variable %q", name)
+		}
+
+		val, defined := newURLTemplateValue(v)
+		if !defined {
+			continue
+		}
+
+		if first {
+			b.WriteString(op.first)
+			first = false
+		} else {
+			b.WriteString(op.sep)
+		}
+
+		switch {
+		case val.list == nil && val.keys == nil:
+			s := val.str
+			if prefix > 0 && utf8.RuneCountInString(s) > prefix {
+				s = string([]rune(s)[:prefix])
+			}
+			if op.named {
+				b.WriteString(escapeURLTemplate(name, true))
+				if s == "" {
+					b.WriteString(op.ifEmpty)
+					continue
+				}
+				b.WriteByte('=')
+			}
+			b.WriteString(escapeURLTemplate(s, op.allowReserved))
+
+		case !explode:
+			if op.named {
+				b.WriteString(escapeURLTemplate(name, true))
+				b.WriteByte('=')
+			}
+			items := val.list
+			if val.keys != nil {
+				items = make([]string, 0, 2*len(val.keys))
+				for i, k := range val.keys {
+					items = append(items, k, val.list[i])
+				}
+			}
+			for i, s := range items {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(escapeURLTemplate(s, op.allowReserved))
+			}
+
+		default:
+			for i, s := range val.list {
+				if i > 0 {
+					b.WriteString(op.sep)
+				}
+				key := name
+				if val.keys != nil {
+					key = val.keys[i]
+				}
+				if op.named || val.keys != nil {
+					b.WriteString(escapeURLTemplate(key, true))
+					if s == "" && op.named {
+						b.WriteString(op.ifEmpty)
+						continue
+					}
+					b.WriteByte('=')
+				}
+				b.WriteString(escapeURLTemplate(s, op.allowReserved))
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseURLTemplateVarSpec(spec string) (name string, explode bool, prefix int, err error) {
+	switch {
+	case strings.HasSuffix(spec, "*"):
+		name, explode = spec[:len(spec)-1], true
+	case strings.Contains(spec, ":"):
+		i := strings.IndexByte(spec, ':')
+		name = spec[:i]
+		_, err = fmt.Sscanf(spec[i+1:], "%d", &prefix)
+		if err != nil || prefix <= 0 || prefix >= 10000 {
+			return "", false, 0, fmt.Errorf("invalid prefix modifier %q", spec)
+		}
+	default:
+		name = spec
+	}
+	if name == "" {
+		return "", false, 0, fmt.Errorf("invalid variable %q", spec)
+	}
+	return name, explode, prefix, nil
+}
+
+// urlTemplateValue is a string, a list or an associative array.
+// An associative array has keys that correspond to values in list.
+type urlTemplateValue struct {
+	str  string
+	list []string
+	keys []string
+}
+
+func newURLTemplateValue(v interface{}) (val urlTemplateValue, defined bool) {
+	if v == nil {
+		return val, false
+	}
+
+	switch v := v.(type) {
+	case string:
+		return urlTemplateValue{str: v}, true
+	case fmt.Stringer:
+		return urlTemplateValue{str: v.String()}, true
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return val, false
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Len() == 0 {
+			return val, false
+		}
+		val.list = make([]string, rv.Len())
+		for i := range val.list {
+			val.list[i] = fmt.Sprint(rv.Index(i).Interface())
+		}
+		return val, true
+	case reflect.Map:
+		if rv.Len() == 0 {
+			return val, false
+		}
+		m := make(map[string]string, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			k := fmt.Sprint(iter.Key().Interface())
+			m[k] = fmt.Sprint(iter.Value().Interface())
+			val.keys = append(val.keys, k)
+		}
+		sort.Strings(val.keys)
+		val.list = make([]string, len(val.keys))
+		for i, k := range val.keys {
+			val.list[i] = m[k]
+		}
+		return val, true
+	default:
+		return urlTemplateValue{str: fmt.Sprint(rv.Interface())}, true
+	}
+}
+
+const upperhex = "0123456789ABCDEF"
+
+// escapeURLTemplate percent-encodes characters other than unreserved characters.
+// If allowReserved is true, reserved characters and percent-encoded triplets are also kept as they are.
+func escapeURLTemplate(s string, allowReserved bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isURLTemplateUnreserved(c):
+			b.WriteByte(c)
+		case allowReserved && strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0:
+			b.WriteByte(c)
+		case allowReserved && c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteString(s[i : i+3])
+			i += 2
+		default:
+			b.WriteByte('%')
+			b.WriteByte(upperhex[c>>4])
+			b.WriteByte(upperhex[c&15])
+		}
+	}
+	return b.String()
+}
+
+func isURLTemplateUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
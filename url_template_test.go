@@ -0,0 +1,152 @@
+package hx_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/izumin5210/hx"
+)
+
+func TestExpandURLTemplate(t *testing.T) {
+	// https://tools.ietf.org/html/rfc6570#section-3.2
+	params := map[string]interface{}{
+		"count": []string{"one", "two", "three"},
+		"dom":   []string{"example", "com"},
+		"dub":   "me/too",
+		"hello": "Hello World!",
+		"half":  "50%",
+		"var":   "value",
+		"who":   "fred",
+		"base":  "http://example.com/home/",
+		"path":  "/foo/bar",
+		"list":  []string{"red", "green", "blue"},
+		"keys":  map[string]string{"semi": ";", "dot": ".", "comma": ","},
+		"v":     6,
+		"x":     1024,
+		"y":     768,
+		"empty": "",
+		"undef": nil,
+	}
+
+	cases := []struct {
+		tmpl string
+		want string
+	}{
+		{tmpl: "{var}", want: "value"},
+		{tmpl: "{hello}", want: "Hello%20World%21"},
+		{tmpl: "{half}", want: "50%25"},
+		{tmpl: "O{empty}X", want: "OX"},
+		{tmpl: "O{undef}X", want: "OX"},
+		{tmpl: "{x,y}", want: "1024,768"},
+		{tmpl: "{x,hello,y}", want: "1024,Hello%20World%21,768"},
+		{tmpl: "?{x,empty}", want: "?1024,"},
+		{tmpl: "?{x,undef}", want: "?1024"},
+		{tmpl: "{var:3}", want: "val"},
+		{tmpl: "{var:30}", want: "value"},
+		{tmpl: "{list}", want: "red,green,blue"},
+		{tmpl: "{list*}", want: "red,green,blue"},
+		{tmpl: "{keys}", want: "comma,%2C,dot,.,semi,%3B"},
+		{tmpl: "{keys*}", want: "comma=%2C,dot=.,semi=%3B"},
+		{tmpl: "{+var}", want: "value"},
+		{tmpl: "{+hello}", want: "Hello%20World!"},
+		{tmpl: "{+half}", want: "50%25"},
+		{tmpl: "{base}index", want: "http%3A%2F%2Fexample.com%2Fhome%2Findex"},
+		{tmpl: "{+base}index", want: "http://example.com/home/index"},
+		{tmpl: "{+path}/here", want: "/foo/bar/here"},
+		{tmpl: "here?ref={+path}", want: "here?ref=/foo/bar"},
+		{tmpl: "{+path:6}/here", want: "/foo/b/here"},
+		{tmpl: "{+keys*}", want: "comma=,,dot=.,semi=;"},
+		{tmpl: "{#var}", want: "#value"},
+		{tmpl: "{#hello}", want: "#Hello%20World!"},
+		{tmpl: "{#path,x}/here", want: "#/foo/bar,1024/here"},
+		{tmpl: "{#list*}", want: "#red,green,blue"},
+		{tmpl: "X{.var}", want: "X.value"},
+		{tmpl: "X{.x,y}", want: "X.1024.768"},
+		{tmpl: "www{.dom*}", want: "www.example.com"},
+		{tmpl: "X{.list*}", want: "X.red.green.blue"},
+		{tmpl: "{/who}", want: "/fred"},
+		{tmpl: "{/who,who}", want: "/fred/fred"},
+		{tmpl: "{/half,who}", want: "/50%25/fred"},
+		{tmpl: "{/var,x}/here", want: "/value/1024/here"},
+		{tmpl: "{/var:1,var}", want: "/v/value"},
+		{tmpl: "{/list*,path:4}", want: "/red/green/blue/%2Ffoo"},
+		{tmpl: "{;who}", want: ";who=fred"},
+		{tmpl: "{;v,empty,who}", want: ";v=6;empty;who=fred"},
+		{tmpl: "{;v,bar,who}", want: ";v=6;who=fred"},
+		{tmpl: "{;list*}", want: ";list=red;list=green;list=blue"},
+		{tmpl: "{;keys*}", want: ";comma=%2C;dot=.;semi=%3B"},
+		{tmpl: "{?x,y}", want: "?x=1024&y=768"},
+		{tmpl: "{?x,y,empty}", want: "?x=1024&y=768&empty="},
+		{tmpl: "{?x,y,undef}", want: "?x=1024&y=768"},
+		{tmpl: "{?list}", want: "?list=red,green,blue"},
+		{tmpl: "{?list*}", want: "?list=red&list=green&list=blue"},
+		{tmpl: "{?keys*}", want: "?comma=%2C&dot=.&semi=%3B"},
+		{tmpl: "?fixed=yes{&x}", want: "?fixed=yes&x=1024"},
+		{tmpl: "{&x,y,empty}", want: "&x=1024&y=768&empty="},
+		{tmpl: "/users/{id}/posts{?page,limit}", want: "/users/foo%2F..%3F/posts?page=2"},
+	}
+
+	params["bar"] = nil
+	params["id"] = "foo/..?"
+	params["page"] = 2
+	params["limit"] = nil
+
+	for _, tc := range cases {
+		t.Run(tc.tmpl, func(t *testing.T) {
+			got, err := hx.ExpandURLTemplate(tc.tmpl, params)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if got != tc.want {
+				t.Errorf("returned %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	for _, tmpl := range []string{"{missing}", "/users/{id", "{}", "{var:0}", "{var:abc}"} {
+		t.Run("invalid "+tmpl, func(t *testing.T) {
+			_, err := hx.ExpandURLTemplate(tmpl, params)
+			if err == nil {
+				t.Error("returned nil, want an error")
+			}
+		})
+	}
+}
+
+func TestURLTemplate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.EscapedPath() + "?" + r.URL.RawQuery))
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	cli := hx.NewClient(hx.BaseURL(u))
+
+	t.Run("simple", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := cli.Get(context.Background(), "",
+			hx.URLTemplate("/users/{id}/posts{?page}", map[string]interface{}{"id": "a/b", "page": 3}),
+			hx.WhenSuccess(hx.AsBytesBuffer(&buf)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := buf.String(), "/users/a%2Fb/posts?page=3"; got != want {
+			t.Errorf("requested %q, want %q", got, want)
+		}
+	})
+
+	t.Run("missing variable", func(t *testing.T) {
+		err := cli.Get(context.Background(), "",
+			hx.URLTemplate("/users/{id}/posts", map[string]interface{}{}),
+		)
+		if err == nil {
+			t.Error("returned nil, want an error")
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package hx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+var DefaultXMLConfig = &XMLConfig{}
+
+type XMLConfig struct {
+	EncodeFunc func(interface{}) (io.Reader, error)
+	DecodeFunc func(io.Reader, interface{}) error
+	// CharsetReader converts non-UTF-8 response bodies into UTF-8.
+	// Charsets are given by the charset parameter of Content-Type,
+	// or by XML declarations (e.g. <?xml version="1.0" encoding="Shift_JIS"?>) if the parameter is absent.
+	// It is not used when DecodeFunc is set.
+	// Decoding responses in non-UTF-8 charsets fails if it is nil.
+	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+}
+
+func (c *XMLConfig) XML(v interface{}) Option {
+	return OptionFunc(func(cfg *Config) error {
+		r, err := c.encode(v)
+		if err != nil {
+			return err
+		}
+		cfg.Body = r
+		cfg.GetBody = nil
+		return contentTypeXML.ApplyOption(cfg)
+	})
+}
+
+func (c *XMLConfig) AsXML(v interface{}) ResponseHandler {
+	return func(r *http.Response, err error) (*http.Response, error) {
+		if r == nil || err != nil {
+			return r, err
+		}
+		if !hasBody(r) {
+			return r, nil
+		}
+
+		defer r.Body.Close()
+		err = c.decodeResponse(r, v)
+		if err != nil {
+			return nil, &ResponseError{Response: r, Err: err}
+		}
+		return r, nil
+	}
+}
+
+func (c *XMLConfig) AsXMLError(dst error) ResponseHandler {
+	return func(r *http.Response, err error) (*http.Response, error) {
+		if r == nil || err != nil {
+			return r, err
+		}
+		if !hasBody(r) {
+			return nil, &ResponseError{Response: r, Err: dst}
+		}
+		err = c.decodeResponse(r, dst)
+		if err != nil {
+			return nil, &ResponseError{Response: r, Err: err}
+		}
+		return nil, &ResponseError{Response: r, Err: dst}
+	}
+}
+
+func (c *XMLConfig) encode(v interface{}) (io.Reader, error) {
+	if f := c.EncodeFunc; f != nil {
+		return f(v)
+	}
+
+	switch v := v.(type) {
+	case io.Reader:
+		return v, nil
+	case string:
+		return strings.NewReader(v), nil
+	case []byte:
+		return bytes.NewReader(v), nil
+	default:
+		data, err := xml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	}
+}
+
+func (c *XMLConfig) decode(r io.Reader, v interface{}) error {
+	if f := c.DecodeFunc; f != nil {
+		return f(r, v)
+	}
+
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = c.CharsetReader
+	return dec.Decode(v)
+}
+
+// decodeResponse decodes a response body in the charset given by Content-Type.
+func (c *XMLConfig) decodeResponse(r *http.Response, v interface{}) error {
+	if c.DecodeFunc != nil {
+		return c.decode(r.Body, v)
+	}
+
+	_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	charset := params["charset"]
+	if charset == "" || strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "us-ascii") {
+		return c.decode(r.Body, v)
+	}
+	if c.CharsetReader == nil {
+		return fmt.Errorf("xml: charset %q in Content-Type needs XMLConfig.CharsetReader", charset)
+	}
+
+	body, err := c.CharsetReader(charset, r.Body)
+	if err != nil {
+		return err
+	}
+	dec := xml.NewDecoder(body)
+	// Content-Type takes precedence over XML declarations, and the body has already been converted.
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	return dec.Decode(v)
+}
@@ -0,0 +1,215 @@
+package hx_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/izumin5210/hx"
+)
+
+type xmlFault struct {
+	XMLName xml.Name `xml:"fault"`
+	Code    string   `xml:"code"`
+}
+
+func (f *xmlFault) Error() string { return f.Code }
+
+func TestXML(t *testing.T) {
+	type Content struct {
+		XMLName xml.Name `xml:"content"`
+		Body    string   `xml:"body"`
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/echo":
+			if r.Header.Get("Content-Type") != "application/xml" {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			io.Copy(w, r.Body)
+		case r.Method == http.MethodGet && r.URL.Path == "/latin1":
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><content><body>caf\xe9</body></content>"))
+		case r.Method == http.MethodGet && r.URL.Path == "/latin1-content-type":
+			w.Header().Set("Content-Type", "application/xml; charset=ISO-8859-1")
+			w.Write([]byte("<content><body>caf\xe9</body></content>"))
+		case r.Method == http.MethodGet && r.URL.Path == "/latin1-fault":
+			w.Header().Set("Content-Type", "application/xml; charset=ISO-8859-1")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><fault><code>caf\xe9</code></fault>"))
+		case r.Method == http.MethodGet && r.URL.Path == "/fault":
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<fault><code>InvalidArgument</code></fault>`))
+		case r.Method == http.MethodGet && r.URL.Path == "/broken":
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(`<content><body>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("simple", func(t *testing.T) {
+		var got Content
+		err := hx.Post(context.Background(), ts.URL+"/echo",
+			hx.XML(&Content{Body: "hello"}),
+			hx.WhenSuccess(hx.AsXML(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := got.Body, "hello"; got != want {
+			t.Errorf("received %q, want %q", got, want)
+		}
+	})
+
+	t.Run("custom encoder", func(t *testing.T) {
+		var got Content
+		cfg := &hx.XMLConfig{
+			EncodeFunc: func(v interface{}) (io.Reader, error) {
+				return strings.NewReader(`<content><body>overwritten</body></content>`), nil
+			},
+		}
+		err := hx.Post(context.Background(), ts.URL+"/echo",
+			cfg.XML(&Content{Body: "hello"}),
+			hx.WhenSuccess(hx.AsXML(&got)),
+			hx.WhenFailure(hx.AsError()),
+		)
+		if err != nil {
+			t.Errorf("returned %v, want nil", err)
+		}
+		if got, want := got.Body, "overwritten"; got != want {
+			t.Errorf("received %q, want %q", got, want)
+		}
+	})
+
+	t.Run("charset", func(t *testing.T) {
+		t.Run("without CharsetReader", func(t *testing.T) {
+			var got Content
+			err := hx.Get(context.Background(), ts.URL+"/latin1",
+				hx.WhenSuccess(hx.AsXML(&got)),
+				hx.WhenFailure(hx.AsError()),
+			)
+			var respErr *hx.ResponseError
+			if !errors.As(err, &respErr) {
+				t.Errorf("returned %v, want *hx.ResponseError", err)
+			}
+		})
+
+		t.Run("with CharsetReader", func(t *testing.T) {
+			var got Content
+			cfg := &hx.XMLConfig{CharsetReader: latin1Reader}
+			err := hx.Get(context.Background(), ts.URL+"/latin1",
+				hx.WhenSuccess(cfg.AsXML(&got)),
+				hx.WhenFailure(hx.AsError()),
+			)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if got, want := got.Body, "café"; got != want {
+				t.Errorf("received %q, want %q", got, want)
+			}
+		})
+
+		t.Run("Content-Type without CharsetReader", func(t *testing.T) {
+			var got Content
+			err := hx.Get(context.Background(), ts.URL+"/latin1-content-type",
+				hx.WhenSuccess(hx.AsXML(&got)),
+				hx.WhenFailure(hx.AsError()),
+			)
+			var respErr *hx.ResponseError
+			if !errors.As(err, &respErr) {
+				t.Errorf("returned %v, want *hx.ResponseError", err)
+			}
+		})
+
+		t.Run("Content-Type with CharsetReader", func(t *testing.T) {
+			var got Content
+			cfg := &hx.XMLConfig{CharsetReader: latin1Reader}
+			err := hx.Get(context.Background(), ts.URL+"/latin1-content-type",
+				hx.WhenSuccess(cfg.AsXML(&got)),
+				hx.WhenFailure(hx.AsError()),
+			)
+			if err != nil {
+				t.Errorf("returned %v, want nil", err)
+			}
+			if got, want := got.Body, "café"; got != want {
+				t.Errorf("received %q, want %q", got, want)
+			}
+		})
+
+		t.Run("AsXMLError with Content-Type", func(t *testing.T) {
+			cfg := &hx.XMLConfig{CharsetReader: latin1Reader}
+			err := hx.Get(context.Background(), ts.URL+"/latin1-fault",
+				hx.WhenFailure(cfg.AsXMLError(&xmlFault{})),
+			)
+			var fault *xmlFault
+			if !errors.As(err, &fault) {
+				t.Fatalf("returned %v, want *xmlFault", err)
+			}
+			if got, want := fault.Code, "café"; got != want {
+				t.Errorf("received %q, want %q", got, want)
+			}
+		})
+	})
+
+	t.Run("AsXMLError", func(t *testing.T) {
+		err := hx.Get(context.Background(), ts.URL+"/fault",
+			hx.WhenSuccess(hx.AsXML(&Content{})),
+			hx.WhenStatus(hx.AsXMLError(&xmlFault{}), http.StatusBadRequest),
+			hx.WhenFailure(hx.AsError()),
+		)
+		var (
+			fault   *xmlFault
+			respErr *hx.ResponseError
+		)
+		if !errors.As(err, &fault) || !errors.As(err, &respErr) {
+			t.Fatalf("returned %v, want *xmlFault wrapped with *hx.ResponseError", err)
+		}
+		if got, want := fault.Code, "InvalidArgument"; got != want {
+			t.Errorf("received %q, want %q", got, want)
+		}
+		if got, want := respErr.StatusCode(), http.StatusBadRequest; got != want {
+			t.Errorf("received status %d, want %d", got, want)
+		}
+	})
+
+	t.Run("failed to decode response", func(t *testing.T) {
+		err := hx.Get(context.Background(), ts.URL+"/broken",
+			hx.WhenSuccess(hx.AsXML(&Content{})),
+			hx.WhenFailure(hx.AsError()),
+		)
+		var respErr *hx.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("returned %v, want *hx.ResponseError", err)
+		}
+	})
+}
+
+func latin1Reader(charset string, input io.Reader) (io.Reader, error) {
+	if !strings.EqualFold(charset, "ISO-8859-1") {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, b := range data {
+		buf.WriteRune(rune(b))
+	}
+	return &buf, nil
+}